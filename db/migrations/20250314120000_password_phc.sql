@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- New hashes are self-describing PHC strings with the salt embedded;
+-- password_salt is only kept for hashes written before this migration and is
+-- cleared when those users are rehashed on login.
+ALTER TABLE users ALTER COLUMN password_hash TYPE TEXT;
+ALTER TABLE users ALTER COLUMN password_salt SET DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users ALTER COLUMN password_salt DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN password_hash TYPE VARCHAR(255);
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Placeholder author for articles kept after their author deleted the
+-- account. It can never log in: the password hash is not valid and the
+-- account is suspended.
+INSERT INTO users (id, username, email, password_hash, password_salt, bio, image, suspended_at)
+VALUES ('00000000-0000-0000-0000-000000000000', '[deleted]', 'deleted@users.invalid', '!', '', '', '', now())
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM users WHERE id = '00000000-0000-0000-0000-000000000000';
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE article ADD COLUMN author_id UUID;
+UPDATE article a SET author_id = u.id FROM users u WHERE u.username = a.author;
+ALTER TABLE article
+    ALTER COLUMN author_id SET NOT NULL,
+    ADD CONSTRAINT fk_article_author_id FOREIGN KEY (author_id) REFERENCES users(id),
+    DROP CONSTRAINT fk_author,
+    DROP COLUMN author;
+CREATE INDEX IF NOT EXISTS article_author_id_idx ON article (author_id);
+
+-- Usernames given up by a rename or account deletion. While reserved_until
+-- is in the future nobody else can take the name, and if the user still
+-- exists their old profile URL redirects to the current one.
+CREATE TABLE IF NOT EXISTS username_history (
+    username VARCHAR(255) PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    released_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reserved_until TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS username_history_user_id_idx ON username_history (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS username_history;
+ALTER TABLE article ADD COLUMN author VARCHAR(255);
+UPDATE article a SET author = u.username FROM users u WHERE u.id = a.author_id;
+ALTER TABLE article
+    ALTER COLUMN author SET NOT NULL,
+    ADD CONSTRAINT fk_author FOREIGN KEY (author) REFERENCES users(username),
+    DROP CONSTRAINT fk_article_author_id,
+    DROP COLUMN author_id;
+-- +goose StatementEnd
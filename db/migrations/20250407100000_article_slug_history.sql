@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Former slugs of an article. Rows follow the article through later slug
+-- changes (ON UPDATE CASCADE) and go away with it (ON DELETE CASCADE).
+CREATE TABLE IF NOT EXISTS article_slug_history (
+    slug VARCHAR(255) PRIMARY KEY,
+    article_slug VARCHAR(255) NOT NULL REFERENCES article(slug) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS article_slug_history_article_slug_idx ON article_slug_history (article_slug);
+CREATE INDEX IF NOT EXISTS article_slug_history_pattern_idx ON article_slug_history (slug text_pattern_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS article_slug_history;
+-- +goose StatementEnd
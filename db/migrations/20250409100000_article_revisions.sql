@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every saved state of an article, numbered from 1 per article. Rows follow
+-- the article through slug changes and go away with it.
+CREATE TABLE IF NOT EXISTS article_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    article_slug VARCHAR(255) NOT NULL REFERENCES article(slug) ON UPDATE CASCADE ON DELETE CASCADE,
+    number INT NOT NULL,
+    editor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    body TEXT NOT NULL,
+    taglist TEXT[],
+    restored_from INT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (article_slug, number)
+);
+
+-- article.updated_at has no time zone yet; read it in the same zone as
+-- 20250418100000 does (UTC unless rwa.legacy_time_zone says otherwise) rather
+-- than the session's.
+INSERT INTO article_revisions (article_slug, number, editor_id, title, description, body, taglist, created_at)
+SELECT slug, 1, author_id, title, description, body, taglist,
+    updated_at AT TIME ZONE COALESCE(NULLIF(current_setting('rwa.legacy_time_zone', true), ''), 'UTC')
+FROM article;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS article_revisions;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE article
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'published'
+        CHECK (status IN ('draft', 'scheduled', 'published', 'unlisted')),
+    ADD COLUMN publish_at TIMESTAMP WITH TIME ZONE,
+    ADD CONSTRAINT article_scheduled_publish_at CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+CREATE INDEX IF NOT EXISTS article_scheduled_idx ON article (publish_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS article_author_status_idx ON article (author_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS article_author_status_idx;
+DROP INDEX IF EXISTS article_scheduled_idx;
+ALTER TABLE article
+    DROP CONSTRAINT IF EXISTS article_scheduled_publish_at,
+    DROP COLUMN IF EXISTS publish_at,
+    DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
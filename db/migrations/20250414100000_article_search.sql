@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every text is indexed with both the English and the Russian stemmer so a
+-- query matches word forms of either language; the title weighs most.
+ALTER TABLE article ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('russian', title), 'A') ||
+    setweight(to_tsvector('english', description), 'B') ||
+    setweight(to_tsvector('russian', description), 'B') ||
+    setweight(to_tsvector('english', body), 'C') ||
+    setweight(to_tsvector('russian', body), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS article_search_vector_idx ON article USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS article_search_vector_idx;
+ALTER TABLE article DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
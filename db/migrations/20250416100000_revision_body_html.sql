@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Rendered HTML of a revision's body, filled in on first request. A
+-- renderer_version other than the running renderer's marks it stale.
+ALTER TABLE article_revisions
+    ADD COLUMN body_html TEXT,
+    ADD COLUMN renderer_version INT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE article_revisions
+    DROP COLUMN IF EXISTS renderer_version,
+    DROP COLUMN IF EXISTS body_html;
+-- +goose StatementEnd
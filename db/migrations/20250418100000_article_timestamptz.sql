@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Article times were stored without a time zone. They are read as UTC unless
+-- the rwa.legacy_time_zone setting names the zone the server wrote them in,
+-- e.g. PGOPTIONS='-c rwa.legacy_time_zone=Europe/Moscow' goose ... up.
+-- An unknown zone name fails the migration before anything changes.
+ALTER TABLE article
+    ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE USING created_at AT TIME ZONE COALESCE(NULLIF(current_setting('rwa.legacy_time_zone', true), ''), 'UTC'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP WITH TIME ZONE USING updated_at AT TIME ZONE COALESCE(NULLIF(current_setting('rwa.legacy_time_zone', true), ''), 'UTC'),
+    ALTER COLUMN created_at SET DEFAULT now(),
+    ALTER COLUMN updated_at SET DEFAULT now();
+
+-- Articles used to be created with zero times (year 1), which also ended up
+-- in their backfilled first revision. Take the earliest real revision time,
+-- or the migration time when there is none.
+UPDATE article a SET created_at = COALESCE(
+    (SELECT MIN(r.created_at) FROM article_revisions r WHERE r.article_slug = a.slug AND r.created_at > '1970-01-01'),
+    now())
+WHERE a.created_at < '1970-01-01';
+
+UPDATE article_revisions r SET created_at = a.created_at
+FROM article a
+WHERE a.slug = r.article_slug AND r.created_at < '1970-01-01';
+
+UPDATE article a SET updated_at = COALESCE(
+    (SELECT MAX(r.created_at) FROM article_revisions r WHERE r.article_slug = a.slug),
+    a.created_at)
+WHERE a.updated_at < '1970-01-01';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE article
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE COALESCE(NULLIF(current_setting('rwa.legacy_time_zone', true), ''), 'UTC'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE COALESCE(NULLIF(current_setting('rwa.legacy_time_zone', true), ''), 'UTC'),
+    ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP;
+-- +goose StatementEnd
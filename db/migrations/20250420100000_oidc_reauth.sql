@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A login state with reauth_user_id set confirms that user's identity
+-- instead of signing in; the callback answers with a short-lived reauth
+-- token that sensitive endpoints accept in place of the password.
+ALTER TABLE oidc_login_states ADD COLUMN reauth_user_id UUID REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS reauth_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reauth_tokens;
+ALTER TABLE oidc_login_states DROP COLUMN IF EXISTS reauth_user_id;
+-- +goose StatementEnd
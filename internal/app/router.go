@@ -5,7 +5,9 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"rwa/internal/config"
 	handler "rwa/internal/handlers"
+	"rwa/internal/model"
 	"rwa/internal/security"
 
 	"github.com/gorilla/mux"
@@ -34,20 +36,111 @@ func Init() http.Handler {
 		panic(err)
 	}
 
-	handlers := handler.NewHandlers(pool, logger)
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("invalid configuration", "error", err)
+		panic(err)
+	}
+	err = security.SetArgon2Params(security.Argon2Params{
+		Time:    uint32(cfg.Argon2Time),
+		Memory:  uint32(cfg.Argon2MemoryKiB),
+		Threads: uint8(cfg.Argon2Threads),
+		KeyLen:  uint32(cfg.Argon2KeyLen),
+		SaltLen: security.DefaultArgon2Params.SaltLen,
+	})
+	if err != nil {
+		logger.Error("invalid argon2 configuration", "error", err)
+		panic(err)
+	}
+	security.SetPasswordPolicy(security.PasswordPolicy{
+		MinLength:        cfg.PasswordMinLength,
+		MaxLength:        cfg.PasswordMaxLength,
+		MinClasses:       cfg.PasswordMinClasses,
+		DisallowPersonal: cfg.PasswordDisallowPersonal,
+		CheckBreached:    cfg.PasswordCheckBreached,
+	})
+	err = security.LoadBreachedPasswords(cfg.BreachedPasswordsFile)
+	if err != nil {
+		logger.Error("failed to load breached passwords", "error", err)
+		panic(err)
+	}
+	handlers := handler.NewHandlers(pool, logger, cfg)
+	if cfg.StatelessTokens() {
+		go handlers.Revocations.Run(ctx, cfg.RevocationRefreshEvery, handlers.UserRepository.GetRevokedTokens, logger)
+	}
+	go handlers.RunPublishScheduler(ctx, cfg.PublishSchedulerEvery)
+	// scoped routes accept session tokens and personal access tokens granted
+	// scope; session routes manage credentials and refuse personal tokens.
+	scoped := func(scope string, hf http.HandlerFunc) http.Handler {
+		return handlers.AuthMiddleware(handlers.RequireScope(scope, hf))
+	}
+	session := func(hf http.HandlerFunc) http.Handler {
+		return handlers.AuthMiddleware(handlers.SessionOnly(hf))
+	}
+	// permitted routes require an interactive session whose role grants
+	// permission.
+	permitted := func(permission string, hf http.HandlerFunc) http.Handler {
+		return handlers.AuthMiddleware(handlers.SessionOnly(handlers.RequirePermission(permission, hf)))
+	}
+
 	r := mux.NewRouter()
 	r.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
 		writer.Write([]byte("Hello, world!"))
 	})
 	r.HandleFunc("/users/login", handlers.LoginUserHandler).Methods(http.MethodPost)
-	r.Handle("/users/logout", handlers.AuthMiddleware(http.HandlerFunc(handlers.LogoutHandler))).Methods(http.MethodPost)
+	r.HandleFunc("/users/login/2fa", handlers.CompleteLoginHandler).Methods(http.MethodPost)
+	r.Handle("/users/logout", session(handlers.LogoutHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/auth/oidc/{provider}/login", handlers.OIDCLoginHandler).Methods(http.MethodGet)
+	r.HandleFunc("/auth/oidc/{provider}/callback", handlers.OIDCCallbackHandler).Methods(http.MethodGet)
+	r.Handle("/user/reauth/oidc/{provider}", session(handlers.StartOIDCReauthHandler)).Methods(http.MethodPost)
 	r.HandleFunc("/users", handlers.UserRegisterHandler).Methods(http.MethodPost)
-	r.Handle("/users", handlers.AuthMiddleware(http.HandlerFunc(handlers.GetUserHandler))).Methods(http.MethodGet)
-	r.Handle("/users", handlers.AuthMiddleware(http.HandlerFunc(handlers.UpdateUserHandler))).Methods(http.MethodPut)
-	r.Handle("/profiles/{username}/follow", handlers.AuthMiddleware(http.HandlerFunc(handlers.FollowHandler))).Methods(http.MethodPost)
-	r.Handle("/profiles/{username}/unfollow", handlers.AuthMiddleware(http.HandlerFunc(handlers.UnFollowHandler))).Methods(http.MethodDelete)
-	r.Handle("/profiles/{username}", handlers.AuthMiddleware(http.HandlerFunc(handlers.CheckProfileHandler))).Methods(http.MethodGet)
-	r.HandleFunc("/articles", handlers.GetArticleHandler).Methods(http.MethodGet)
-	r.Handle("/articles", handlers.AuthMiddleware(http.HandlerFunc(handlers.CreateArticleHandler))).Methods(http.MethodPost)
-	return r
+	r.Handle("/users", scoped(model.ScopeUserRead, handlers.GetUserHandler)).Methods(http.MethodGet)
+	// Personal tokens with user:write may edit bio and image; the handler
+	// keeps email, username and privacy to sessions.
+	r.Handle("/users", scoped(model.ScopeUserWrite, handlers.UpdateUserHandler)).Methods(http.MethodPut)
+	r.Handle("/user", session(handlers.DeleteAccountHandler)).Methods(http.MethodDelete)
+	r.Handle("/user/export", session(handlers.ExportAccountHandler)).Methods(http.MethodGet)
+	r.Handle("/user/password", session(handlers.ChangePasswordHandler)).Methods(http.MethodPut)
+	r.HandleFunc("/user/password/reset", handlers.RequestPasswordResetHandler).Methods(http.MethodPost)
+	r.HandleFunc("/user/password/reset/confirm", handlers.ConfirmPasswordResetHandler).Methods(http.MethodPost)
+	r.HandleFunc("/user/email/confirm", handlers.ConfirmEmailHandler).Methods(http.MethodPost)
+	r.Handle("/user/email/resend", session(handlers.ResendEmailVerificationHandler)).Methods(http.MethodPost)
+	r.Handle("/user/2fa/totp", session(handlers.EnrollTOTPHandler)).Methods(http.MethodPost)
+	r.Handle("/user/2fa/totp/confirm", session(handlers.ConfirmTOTPHandler)).Methods(http.MethodPost)
+	r.Handle("/user/2fa/totp", session(handlers.DisableTOTPHandler)).Methods(http.MethodDelete)
+	r.Handle("/user/tokens", session(handlers.ListPersonalTokensHandler)).Methods(http.MethodGet)
+	r.Handle("/user/tokens", session(handlers.CreatePersonalTokenHandler)).Methods(http.MethodPost)
+	r.Handle("/user/tokens/{id:[0-9a-f-]{36}}", session(handlers.DeletePersonalTokenHandler)).Methods(http.MethodDelete)
+	r.Handle("/profiles/{username}/follow", scoped(model.ScopeProfileWrite, handlers.FollowHandler)).Methods(http.MethodPost)
+	r.Handle("/profiles/{username}/unfollow", scoped(model.ScopeProfileWrite, handlers.UnFollowHandler)).Methods(http.MethodDelete)
+	r.Handle("/profiles/{username}/block", scoped(model.ScopeProfileWrite, handlers.BlockHandler)).Methods(http.MethodPost)
+	r.Handle("/profiles/{username}/block", scoped(model.ScopeProfileWrite, handlers.UnblockHandler)).Methods(http.MethodDelete)
+	r.Handle("/profiles/{username}/mute", scoped(model.ScopeProfileWrite, handlers.MuteHandler)).Methods(http.MethodPost)
+	r.Handle("/profiles/{username}/mute", scoped(model.ScopeProfileWrite, handlers.UnmuteHandler)).Methods(http.MethodDelete)
+	r.Handle("/user/follow-requests", scoped(model.ScopeProfileRead, handlers.ListFollowRequestsHandler)).Methods(http.MethodGet)
+	r.Handle("/user/follow-requests/{username}/approve", scoped(model.ScopeProfileWrite, handlers.ApproveFollowRequestHandler)).Methods(http.MethodPost)
+	r.Handle("/user/follow-requests/{username}/reject", scoped(model.ScopeProfileWrite, handlers.RejectFollowRequestHandler)).Methods(http.MethodPost)
+	r.Handle("/user/blocks", scoped(model.ScopeProfileRead, handlers.ListBlocksHandler)).Methods(http.MethodGet)
+	r.Handle("/user/mutes", scoped(model.ScopeProfileRead, handlers.ListMutesHandler)).Methods(http.MethodGet)
+	r.Handle("/user/audit", session(handlers.MyAuditHandler)).Methods(http.MethodGet)
+	r.Handle("/profiles/{username}", scoped(model.ScopeProfileRead, handlers.CheckProfileHandler)).Methods(http.MethodGet)
+	r.Handle("/admin/users", permitted(model.PermissionUsersList, handlers.ListUsersHandler)).Methods(http.MethodGet)
+	r.Handle("/admin/users/{username}/suspend", permitted(model.PermissionUsersSuspend, handlers.SuspendUserHandler)).Methods(http.MethodPost)
+	r.Handle("/admin/users/{username}/suspend", permitted(model.PermissionUsersSuspend, handlers.UnsuspendUserHandler)).Methods(http.MethodDelete)
+	r.Handle("/admin/users/{username}/role", permitted(model.PermissionUsersRole, handlers.SetUserRoleHandler)).Methods(http.MethodPut)
+	r.Handle("/admin/users/{username}/lockout", permitted(model.PermissionUsersUnlock, handlers.UnlockUserHandler)).Methods(http.MethodDelete)
+	r.Handle("/admin/articles/{slug}", permitted(model.PermissionArticlesDelete, handlers.ForceDeleteArticleHandler)).Methods(http.MethodDelete)
+	r.Handle("/admin/audit", permitted(model.PermissionAuditRead, handlers.AdminAuditHandler)).Methods(http.MethodGet)
+	r.Handle("/articles", handlers.OptionalAuth(http.HandlerFunc(handlers.GetArticleHandler))).Methods(http.MethodGet)
+	r.Handle("/articles", scoped(model.ScopeArticlesWrite, handlers.CreateArticleHandler)).Methods(http.MethodPost)
+	r.Handle("/articles/feed", scoped(model.ScopeArticlesRead, handlers.FeedArticlesHandler)).Methods(http.MethodGet)
+	r.Handle("/articles/search", handlers.OptionalAuth(http.HandlerFunc(handlers.SearchArticlesHandler))).Methods(http.MethodGet)
+	r.Handle("/articles/{slug}", handlers.OptionalAuth(http.HandlerFunc(handlers.GetSingleArticleHandler))).Methods(http.MethodGet)
+	r.Handle("/articles/{slug}", scoped(model.ScopeArticlesWrite, handlers.UpdateArticleHandler)).Methods(http.MethodPut)
+	r.Handle("/articles/{slug}", scoped(model.ScopeArticlesWrite, handlers.DeleteArticleHandler)).Methods(http.MethodDelete)
+	r.Handle("/articles/{slug}/revisions", scoped(model.ScopeArticlesRead, handlers.ListRevisionsHandler)).Methods(http.MethodGet)
+	r.Handle("/articles/{slug}/revisions/diff", scoped(model.ScopeArticlesRead, handlers.DiffRevisionsHandler)).Methods(http.MethodGet)
+	r.Handle("/articles/{slug}/revisions/{number:[0-9]+}", scoped(model.ScopeArticlesRead, handlers.GetRevisionHandler)).Methods(http.MethodGet)
+	r.Handle("/articles/{slug}/revisions/{number:[0-9]+}/restore", scoped(model.ScopeArticlesWrite, handlers.RestoreRevisionHandler)).Methods(http.MethodPost)
+	return handlers.LimitBody(r)
 }
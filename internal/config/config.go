@@ -0,0 +1,253 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TokenValidationDatabase  = "database"
+	TokenValidationStateless = "stateless"
+)
+
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type Config struct {
+	// TokenValidation selects how AuthMiddleware checks session tokens:
+	// "database" looks every token up in the tokens table, "stateless" trusts
+	// the PASETO signature and expiry and consults the revocation cache.
+	TokenValidation        string
+	RevocationRefreshEvery time.Duration
+
+	// Mailer is "log" (default) or "file"; MailerFile is used by the latter.
+	Mailer     string
+	MailerFile string
+	MailFrom   string
+
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	// RequireVerifiedEmail stops users with an unverified email from
+	// publishing articles.
+	RequireVerifiedEmail bool
+
+	TOTPIssuer        string
+	LoginChallengeTTL time.Duration
+
+	// Failed login throttling. Accounts and client IPs are tracked
+	// separately; IPs get a higher lockout threshold since many users can
+	// share one address.
+	LoginFreeAttempts    int
+	LoginBackoffBase     time.Duration
+	LoginBackoffMax      time.Duration
+	LoginLockoutAfter    int
+	LoginIPLockoutAfter  int
+	LoginLockoutDuration time.Duration
+	LoginAttemptWindow   time.Duration
+	// TrustProxyHeaders makes X-Forwarded-For the source of the client IP.
+	// The address is the rightmost entry that is not one of TrustedProxies,
+	// so it is the one appended by the outermost proxy we trust.
+	TrustProxyHeaders bool
+	// TrustedProxies are the addresses of the proxies in front of the
+	// server. When set, X-Forwarded-For is only honoured for requests
+	// coming from one of them.
+	TrustedProxies []netip.Prefix
+
+	// Argon2 cost settings for newly hashed passwords.
+	Argon2Time      int
+	Argon2MemoryKiB int
+	Argon2Threads   int
+	Argon2KeyLen    int
+
+	PasswordMinLength        int
+	PasswordMaxLength        int
+	PasswordMinClasses       int
+	PasswordDisallowPersonal bool
+	PasswordCheckBreached    bool
+	// BreachedPasswordsFile replaces the bundled list of breached password
+	// SHA-1 digests.
+	BreachedPasswordsFile string
+
+	// OIDCProviders are read from OIDC_PROVIDERS (comma separated names) and
+	// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL, _SCOPES.
+	OIDCProviders []OIDCProvider
+	// OIDCLinkVerifiedEmail links a first-time external login to an existing
+	// account with the same email when both sides have verified it.
+	OIDCLinkVerifiedEmail bool
+
+	// AccountDeletionArticles is what happens to a deleted user's articles:
+	// "anonymize" keeps them under a placeholder author, "delete" removes them.
+	AccountDeletionArticles string
+	// UsernameReservation is how long a released username stays reserved
+	// and, after a rename, redirects to the new profile.
+	UsernameReservation time.Duration
+
+	// PublishSchedulerEvery is how often scheduled articles whose publish
+	// time has passed are made public.
+	PublishSchedulerEvery time.Duration
+
+	// MaxRequestBody caps the size of request bodies in bytes.
+	MaxRequestBody int
+}
+
+// Load reads the configuration from the environment and rejects values that
+// are out of range for the setting they configure.
+func Load() (Config, error) {
+	trustedProxies, proxiesErr := getPrefixes("TRUSTED_PROXIES")
+	cfg := Config{
+		TokenValidation:          getString("TOKEN_VALIDATION", TokenValidationDatabase),
+		RevocationRefreshEvery:   getDuration("TOKEN_REVOCATION_REFRESH", 30*time.Second),
+		Mailer:                   getString("MAILER", "log"),
+		MailerFile:               getString("MAILER_FILE", "mail.log"),
+		MailFrom:                 getString("MAIL_FROM", "noreply@conduit.local"),
+		PasswordResetTTL:         getDuration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL:     getDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		RequireVerifiedEmail:     getBool("REQUIRE_VERIFIED_EMAIL", false),
+		TOTPIssuer:               getString("TOTP_ISSUER", "Conduit"),
+		LoginChallengeTTL:        getDuration("LOGIN_CHALLENGE_TTL", 5*time.Minute),
+		LoginFreeAttempts:        getInt("LOGIN_FREE_ATTEMPTS", 3),
+		LoginBackoffBase:         getDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginBackoffMax:          getDuration("LOGIN_BACKOFF_MAX", 5*time.Minute),
+		LoginLockoutAfter:        getInt("LOGIN_LOCKOUT_AFTER", 10),
+		LoginIPLockoutAfter:      getInt("LOGIN_IP_LOCKOUT_AFTER", 100),
+		LoginLockoutDuration:     getDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginAttemptWindow:       getDuration("LOGIN_ATTEMPT_WINDOW", time.Hour),
+		TrustProxyHeaders:        getBool("TRUST_PROXY_HEADERS", false),
+		TrustedProxies:           trustedProxies,
+		Argon2Time:               getInt("ARGON2_TIME", 1),
+		Argon2MemoryKiB:          getInt("ARGON2_MEMORY_KIB", 64*1024),
+		Argon2Threads:            getInt("ARGON2_THREADS", 4),
+		Argon2KeyLen:             getInt("ARGON2_KEY_LEN", 32),
+		PasswordMinLength:        getInt("PASSWORD_MIN_LENGTH", 5),
+		PasswordMaxLength:        getInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordMinClasses:       getInt("PASSWORD_MIN_CLASSES", 1),
+		PasswordDisallowPersonal: getBool("PASSWORD_DISALLOW_PERSONAL", true),
+		PasswordCheckBreached:    getBool("PASSWORD_CHECK_BREACHED", true),
+		BreachedPasswordsFile:    getString("BREACHED_PASSWORDS_FILE", ""),
+		OIDCProviders:            loadOIDCProviders(),
+		OIDCLinkVerifiedEmail:    getBool("OIDC_LINK_VERIFIED_EMAIL", true),
+		AccountDeletionArticles:  getString("ACCOUNT_DELETION_ARTICLES", "anonymize"),
+		UsernameReservation:      getDuration("USERNAME_RESERVATION", 30*24*time.Hour),
+		PublishSchedulerEvery:    getDuration("PUBLISH_SCHEDULER_INTERVAL", 30*time.Second),
+		MaxRequestBody:           getInt("MAX_REQUEST_BODY_BYTES", 1<<20),
+	}
+	return cfg, errors.Join(proxiesErr, cfg.validate())
+}
+
+// validate checks the settings that are narrowed to smaller integer types
+// later on, so a value such as ARGON2_THREADS=256 is refused instead of
+// wrapping around.
+func (c Config) validate() error {
+	var errs []error
+	check := func(key string, v, lo, hi int) {
+		if v < lo || v > hi {
+			errs = append(errs, fmt.Errorf("%s must be between %d and %d, got %d", key, lo, hi, v))
+		}
+	}
+	check("ARGON2_TIME", c.Argon2Time, 1, math.MaxUint32)
+	check("ARGON2_THREADS", c.Argon2Threads, 1, math.MaxUint8)
+	check("ARGON2_MEMORY_KIB", c.Argon2MemoryKiB, 8*max(c.Argon2Threads, 1), math.MaxUint32)
+	check("ARGON2_KEY_LEN", c.Argon2KeyLen, 16, 1024)
+	return errors.Join(errs...)
+}
+
+func (c Config) StatelessTokens() bool {
+	return c.TokenValidation == TokenValidationStateless
+}
+
+func loadOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProvider{
+			Name:         strings.ToLower(name),
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		})
+	}
+	return providers
+}
+
+func getString(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// getPrefixes reads a comma separated list of CIDR prefixes or single
+// addresses.
+func getPrefixes(key string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(v); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not an address or CIDR prefix", key, v)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+func getBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+func getInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadArgon2Ranges(t *testing.T) {
+	cases := []struct {
+		key   string
+		value string
+		ok    bool
+	}{
+		{"ARGON2_THREADS", "4", true},
+		{"ARGON2_THREADS", "255", true},
+		{"ARGON2_THREADS", "256", false},
+		{"ARGON2_THREADS", "0", false},
+		{"ARGON2_TIME", "0", false},
+		{"ARGON2_TIME", "4294967296", false},
+		{"ARGON2_MEMORY_KIB", "31", false},
+		{"ARGON2_MEMORY_KIB", "32", true},
+		{"ARGON2_MEMORY_KIB", "4294967296", false},
+		{"ARGON2_KEY_LEN", "15", false},
+		{"ARGON2_KEY_LEN", "-1", false},
+	}
+	for _, c := range cases {
+		t.Run(c.key+"="+c.value, func(t *testing.T) {
+			t.Setenv(c.key, c.value)
+			_, err := Load()
+			if c.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !c.ok && (err == nil || !strings.Contains(err.Error(), c.key)) {
+				t.Fatalf("error = %v, want one naming %s", err, c.key)
+			}
+		})
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1,::ffff:172.16.0.1")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.1/32", "172.16.0.1/32"}
+	if len(cfg.TrustedProxies) != len(want) {
+		t.Fatalf("TrustedProxies = %v, want %v", cfg.TrustedProxies, want)
+	}
+	for i, p := range cfg.TrustedProxies {
+		if p.String() != want[i] {
+			t.Errorf("TrustedProxies[%d] = %s, want %s", i, p, want[i])
+		}
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,proxy.local")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "TRUSTED_PROXIES") {
+		t.Fatalf("error = %v, want one naming TRUSTED_PROXIES", err)
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+	"rwa/internal/security"
+	"time"
+)
+
+// confirmIdentity checks the proof a signed-in user gives for a sensitive
+// action: their current password or, for accounts that sign in through an
+// identity provider, a reauth token from a fresh OIDC re-authentication. A
+// password goes through the same throttle as logins, so a stolen session
+// cannot be used to guess it. It writes the error response itself.
+func (h *Handlers) confirmIdentity(w http.ResponseWriter, r *http.Request, user model.UserTableDB, password string, reauthToken string, op string) bool {
+	if reauthToken != "" {
+		err := h.UserRepository.ConsumeReauthToken(user.ID, security.HashOpaqueToken(reauthToken))
+		if errors.Is(err, repository.ErrChallengeInvalid) {
+			h.log.Warn(op+": reauth token rejected", "uid", user.ID)
+			HandleError(w, "Re-authentication is invalid or expired", http.StatusForbidden)
+			return false
+		}
+		if err != nil {
+			h.log.Error(op+": failed to check reauth token", "error", err, "uid", user.ID)
+			HandleError(w, "Failed to confirm your identity", http.StatusInternalServerError)
+			return false
+		}
+		return true
+	}
+
+	ip := h.clientIP(r)
+	lockedUntil, err := h.LoginAttemptRepository.LockedUntil(loginKeys(user.Email, ip)...)
+	if err != nil {
+		h.log.Error(op+": failed to check login throttling", "error", err, "uid", user.ID)
+		HandleError(w, "Failed to confirm your identity", http.StatusInternalServerError)
+		return false
+	}
+	if !lockedUntil.IsZero() {
+		h.log.Warn(op+": password check throttled", "uid", user.ID, "ip", ip, "until", lockedUntil)
+		writeTooManyRequests(w, lockedUntil, "Too many failed password attempts, try again later")
+		return false
+	}
+	if !security.ComparePasswords(password, user.PasswordHash, user.PasswordSalt) {
+		h.log.Warn(op+": password mismatch", "uid", user.ID)
+		h.recordLoginFailure(user.Email, ip)
+		HandleError(w, "Password is incorrect", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// DeleteAccountHandler deletes the caller's account after confirming their
+// identity with the password or an OIDC re-authentication. Articles are kept or removed according to the configured policy.
+func (h *Handlers) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.DeleteAccountHandler"
+
+	payload := model.DeleteAccountRequest{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(payload)
+	if err != nil {
+		HandleFieldErrors(w, fieldErrors(err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	uid := r.Context().Value("uid").(string)
+	user, err := h.UserRepository.GetUserForUpdate(uid)
+	if err != nil {
+		h.log.Error(op+": failed to get user", "error", err, "uid", uid)
+		HandleError(w, "Failed to retrieve user data", http.StatusUnauthorized)
+		return
+	}
+
+	if !h.confirmIdentity(w, r, user, payload.User.Password, payload.User.ReauthToken, op) {
+		return
+	}
+
+	policy := h.cfg.AccountDeletionArticles
+	if policy != model.ArticlesDelete {
+		policy = model.ArticlesAnonymize
+	}
+	if !h.audit(w, r, model.AuditUserDeleted, model.AuditTargetUser, uid, map[string]any{"username": user.Username, "articles": policy}) {
+		return
+	}
+
+	// Revoke first: once the user row is gone stateless validation has no
+	// other way to learn the tokens are dead.
+	if err := h.revokeUserSessions(uid, ""); err != nil {
+		h.log.Error(op+": failed to revoke sessions", "error", err, "uid", uid)
+		HandleError(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.UserRepository.DeleteAccount(uid, policy, time.Now().Add(h.cfg.UsernameReservation))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			HandleError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error(op+": failed to delete account", "error", err, "uid", uid)
+		HandleError(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.LoginAttemptRepository.Reset(accountKey(user.Email)); err != nil {
+		h.log.Error(op+": failed to clear login attempts", "error", err, "uid", uid)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.log.Info(op+": account deleted", "uid", uid, "articles", policy)
+	return
+}
+
+// ExportAccountHandler returns everything stored about the caller as a JSON
+// file download.
+func (h *Handlers) ExportAccountHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ExportAccountHandler"
+
+	uid := r.Context().Value("uid").(string)
+	export, err := h.UserRepository.ExportUser(uid)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			HandleError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error(op+": failed to export user", "error", err, "uid", uid)
+		HandleError(w, "Failed to export account data", http.StatusInternalServerError)
+		return
+	}
+
+	if !h.audit(w, r, model.AuditUserExported, model.AuditTargetUser, uid, nil) {
+		return
+	}
+
+	filename := fmt.Sprintf("conduit-export-%s-%s.json", export.User.Username, export.ExportedAt.Format("20060102"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		h.log.Error(op+": failed to encode export", "error", err, "uid", uid)
+		return
+	}
+	h.log.Info(op+": account data exported", "uid", uid)
+	return
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"rwa/internal/model"
+	"testing"
+	"time"
+)
+
+func TestDeleteAccountRequestNeedsProof(t *testing.T) {
+	v := newValidator()
+	cases := []struct {
+		password, reauth string
+		ok               bool
+	}{
+		{"secret", "", true},
+		{"", "token", true},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		req := model.DeleteAccountRequest{}
+		req.User.Password, req.User.ReauthToken = c.password, c.reauth
+		if err := v.Struct(req); (err == nil) != c.ok {
+			t.Errorf("password=%q reauthToken=%q: err = %v", c.password, c.reauth, err)
+		}
+	}
+}
+
+// TestConfirmIdentityIsThrottled checks that wrong passwords given to
+// confirm an action count like failed logins.
+func TestConfirmIdentityIsThrottled(t *testing.T) {
+	h := testHandlers(t)
+	h.cfg.LoginFreeAttempts = 1
+	h.cfg.LoginBackoffBase = time.Minute
+	h.cfg.LoginBackoffMax = time.Hour
+	h.cfg.LoginAttemptWindow = time.Hour
+	user := newTestUser(t, h)
+	t.Cleanup(func() {
+		h.LoginAttemptRepository.Reset(accountKey(user.Email))
+		h.LoginAttemptRepository.Reset(ipKey(h.clientIP(httptest.NewRequest(http.MethodDelete, "/user", nil))))
+	})
+
+	want := []int{http.StatusForbidden, http.StatusForbidden, http.StatusTooManyRequests}
+	for i, code := range want {
+		rec := httptest.NewRecorder()
+		h.confirmIdentity(rec, httptest.NewRequest(http.MethodDelete, "/user", nil), user, "wrong password", "", "test")
+		if rec.Code != code {
+			t.Errorf("attempt %d: status = %d, want %d", i+1, rec.Code, code)
+		}
+	}
+
+	// Even the right password waits out the delay.
+	rec := httptest.NewRecorder()
+	if h.confirmIdentity(rec, httptest.NewRequest(http.MethodDelete, "/user", nil), user, "correct horse battery", "", "test") {
+		t.Error("throttled password check succeeded")
+	}
+}
@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// adminTarget resolves the {username} path variable, writing a 404 or 500
+// response when it cannot.
+func (h *Handlers) adminTarget(w http.ResponseWriter, r *http.Request, op string) (model.UserTableDB, bool) {
+	userName := mux.Vars(r)["username"]
+	user, err := h.UserRepository.GetUserForAuth("", userName)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			HandleError(w, "User not found", http.StatusNotFound)
+			return model.UserTableDB{}, false
+		}
+		h.log.Error(op+": failed to get user", "error", err, "username", userName)
+		HandleError(w, "Internal server error", http.StatusInternalServerError)
+		return model.UserTableDB{}, false
+	}
+	return user, true
+}
+
+// parsePage reads the limit and offset query parameters, adding problems to
+// errs.
+func parsePage(q url.Values, errs map[string][]string) (int, int) {
+	limit, offset := defaultPageSize, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			errs["limit"] = append(errs["limit"], "must be between 1 and "+strconv.Itoa(maxPageSize))
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			errs["offset"] = append(errs["offset"], "must be a non-negative number")
+		}
+		offset = n
+	}
+	return limit, offset
+}
+
+func writeAdminUser(w http.ResponseWriter, user model.AdminUser) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.AdminUserResponse{User: user})
+}
+
+// UnlockUserHandler clears the failed-login state of an account.
+func (h *Handlers) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.UnlockUserHandler"
+
+	user, ok := h.adminTarget(w, r, op)
+	if !ok {
+		return
+	}
+
+	if !h.audit(w, r, model.AuditAdminUnlocked, model.AuditTargetUser, user.ID, nil) {
+		return
+	}
+	err := h.LoginAttemptRepository.Reset(accountKey(user.Email))
+	if err != nil {
+		h.log.Error(op+": failed to unlock user", "error", err, "uid", user.ID)
+		HandleError(w, "Failed to unlock user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	h.log.Info(op+": user unlocked", "uid", user.ID)
+	return
+}
+
+// ListUsersHandler pages through users, optionally filtered by role,
+// suspension state and a username/email substring.
+func (h *Handlers) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ListUsersHandler"
+
+	q := r.URL.Query()
+	errs := map[string][]string{}
+	limit, offset := parsePage(q, errs)
+	filter := model.AdminUserFilter{
+		Role:   q.Get("role"),
+		Query:  q.Get("q"),
+		Limit:  limit,
+		Offset: offset,
+	}
+	if v := q.Get("suspended"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs["suspended"] = append(errs["suspended"], "must be true or false")
+		}
+		filter.Suspended = &b
+	}
+	if filter.Role != "" && !slices.Contains(model.Roles, filter.Role) {
+		errs["role"] = append(errs["role"], "is not a known role")
+	}
+	if len(errs) > 0 {
+		HandleFieldErrors(w, errs, http.StatusUnprocessableEntity)
+		return
+	}
+
+	users, total, err := h.UserRepository.ListUsers(filter)
+	if err != nil {
+		h.log.Error(op+": failed to list users", "error", err)
+		HandleError(w, "Failed to list users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.AdminUsersResponse{Users: users, UsersCount: total})
+	return
+}
+
+// SuspendUserHandler blocks an account from authenticating and ends its
+// sessions. Personal access tokens are kept but rejected while suspended.
+func (h *Handlers) SuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.SuspendUserHandler"
+
+	user, ok := h.adminTarget(w, r, op)
+	if !ok {
+		return
+	}
+	actor := r.Context().Value("uid").(string)
+	if user.ID == actor {
+		HandleError(w, "You cannot suspend yourself", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !h.audit(w, r, model.AuditAdminSuspended, model.AuditTargetUser, user.ID, nil) {
+		return
+	}
+	updated, err := h.UserRepository.SetSuspended(user.ID, true)
+	if err != nil {
+		h.log.Error(op+": failed to suspend user", "error", err, "uid", user.ID)
+		HandleError(w, "Failed to suspend user", http.StatusInternalServerError)
+		return
+	}
+	// Stateless validation only learns about the suspension through the
+	// revocation list, so the sessions must be gone before we answer.
+	if err := h.revokeUserSessions(user.ID, ""); err != nil {
+		h.log.Error(op+": failed to revoke sessions", "error", err, "uid", user.ID)
+		HandleError(w, "Failed to end the user's sessions", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminUser(w, updated)
+	h.log.Info(op+": user suspended", "uid", user.ID, "by", actor)
+	return
+}
+
+func (h *Handlers) UnsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.UnsuspendUserHandler"
+
+	user, ok := h.adminTarget(w, r, op)
+	if !ok {
+		return
+	}
+
+	if !h.audit(w, r, model.AuditAdminUnsuspended, model.AuditTargetUser, user.ID, nil) {
+		return
+	}
+	updated, err := h.UserRepository.SetSuspended(user.ID, false)
+	if err != nil {
+		h.log.Error(op+": failed to unsuspend user", "error", err, "uid", user.ID)
+		HandleError(w, "Failed to unsuspend user", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminUser(w, updated)
+	h.log.Info(op+": user unsuspended", "uid", user.ID, "by", r.Context().Value("uid"))
+	return
+}
+
+func (h *Handlers) SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.SetUserRoleHandler"
+
+	payload := model.SetRoleRequest{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+	err = h.V.Struct(payload)
+	if err != nil {
+		HandleFieldErrors(w, fieldErrors(err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	user, ok := h.adminTarget(w, r, op)
+	if !ok {
+		return
+	}
+	actor := r.Context().Value("uid").(string)
+	if user.ID == actor && payload.User.Role != model.RoleAdmin {
+		HandleError(w, "You cannot remove your own admin role", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !h.audit(w, r, model.AuditAdminRoleChanged, model.AuditTargetUser, user.ID, map[string]any{"from": user.Role, "to": payload.User.Role}) {
+		return
+	}
+	updated, err := h.UserRepository.SetRole(user.ID, payload.User.Role)
+	if err != nil {
+		h.log.Error(op+": failed to set role", "error", err, "uid", user.ID)
+		HandleError(w, "Failed to set role", http.StatusInternalServerError)
+		return
+	}
+	// Session tokens carry the role; revoke them so the change takes effect
+	// now rather than when they expire.
+	if updated.Role != user.Role {
+		if err := h.revokeUserSessions(user.ID, ""); err != nil {
+			h.log.Error(op+": failed to revoke sessions", "error", err, "uid", user.ID)
+			HandleError(w, "Failed to end the user's sessions", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeAdminUser(w, updated)
+	h.log.Info(op+": role changed", "uid", user.ID, "role", updated.Role, "by", actor)
+	return
+}
+
+// ForceDeleteArticleHandler removes any article regardless of its author.
+func (h *Handlers) ForceDeleteArticleHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ForceDeleteArticleHandler"
+
+	slug := mux.Vars(r)["slug"]
+	_, err := h.ArticleRepository.GetArticleBySlug(slug)
+	if errors.Is(err, repository.ErrArticleNotFound) {
+		HandleError(w, "Article not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.log.Error(op+": failed to get article", "error", err, "slug", slug)
+		HandleError(w, "Failed to delete article", http.StatusInternalServerError)
+		return
+	}
+	if !h.audit(w, r, model.AuditAdminArticleDeleted, model.AuditTargetArticle, slug, nil) {
+		return
+	}
+	err = h.ArticleRepository.DeleteArticle(slug)
+	if err != nil {
+		if errors.Is(err, repository.ErrArticleNotFound) {
+			HandleError(w, "Article not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error(op+": failed to delete article", "error", err, "slug", slug)
+		HandleError(w, "Failed to delete article", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.log.Info(op+": article deleted by moderator", "slug", slug, "by", r.Context().Value("uid"))
+	return
+}
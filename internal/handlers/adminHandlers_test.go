@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// TestAdminCannotLockThemselvesOut checks that an admin can neither suspend
+// their own account nor drop their own admin role.
+func TestAdminCannotLockThemselvesOut(t *testing.T) {
+	h := testHandlers(t)
+	admin := newTestUser(t, h)
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"suspend", h.SuspendUserHandler, ""},
+		{"demote", h.SetUserRoleHandler, `{"user":{"role":"moderator"}}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/users/"+admin.Username, strings.NewReader(c.body))
+			req = mux.SetURLVars(req, map[string]string{"username": admin.Username})
+			req = req.WithContext(context.WithValue(req.Context(), "uid", admin.ID))
+			rec := httptest.NewRecorder()
+			c.handler(rec, req)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+			}
+		})
+	}
+
+	user, err := h.UserRepository.GetUserForAuth("", admin.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.SuspendedAt != nil {
+		t.Error("admin suspended themselves")
+	}
+}
@@ -2,10 +2,18 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"rwa/internal/markdown"
 	"rwa/internal/model"
 	"rwa/internal/pkg"
+	"rwa/internal/repository"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/gorilla/mux"
 )
 
 func (h *Handlers) CreateArticleHandler(w http.ResponseWriter, r *http.Request) {
@@ -19,67 +27,64 @@ func (h *Handlers) CreateArticleHandler(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "User not found", http.StatusUnauthorized)
 		return
 	}
+	if h.cfg.RequireVerifiedEmail && !user.EmailVerified {
+		h.log.With("op", op, "uid", uid).Warn("Unverified user tried to publish")
+		HandleError(w, "Email address must be verified before publishing", http.StatusForbidden)
+		return
+	}
 	request := model.CreateArticleRequest{}
 	err = json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
 		h.log.With("op", op).Error("Failed to decode request body", "error", err)
-		http.Error(w, "Failed to decode request body", http.StatusUnprocessableEntity)
+		handleDecodeError(w, err, "Failed to decode request body")
 		return
 	}
-	index := 1
-	slug := pkg.Slugify(request.Article.Title, index)
-	var shouldFindSlug bool = true
-	for shouldFindSlug {
-		shouldFindSlug = h.ArticleRepository.IsSlugExists(slug)
-		if shouldFindSlug {
-			index++
-			slug = pkg.Slugify(request.Article.Title, index)
-		} else {
-			shouldFindSlug = false
-		}
+	request.Article.Title = strings.TrimSpace(request.Article.Title)
+	request.Article.Description = strings.TrimSpace(request.Article.Description)
+	request.Article.Body = trimBody(request.Article.Body)
+	request.Article.TagList = model.NormalizeTags(request.Article.TagList)
+	err = h.V.Struct(request)
+	if err != nil {
+		h.log.With("op", op).Warn("Article validation failed", "error", err)
+		HandleFieldErrors(w, fieldErrors(err), http.StatusUnprocessableEntity)
+		return
 	}
 	article := model.DBArticle{
-		Slug:           slug,
-		Title:          request.Article.Title,
-		Description:    request.Article.Description,
-		Body:           request.Article.Body,
-		TagList:        request.Article.TagList,
-		CreatedAt:      time.Time{},
-		UpdatedAt:      time.Time{},
-		FavoritesCount: 0,
-		Author:         user.Username,
-	}
-	err = h.ArticleRepository.CreateArticle(article)
+		Slug:        pkg.SlugBase(request.Article.Title),
+		Title:       request.Article.Title,
+		Description: request.Article.Description,
+		Body:        request.Article.Body,
+		TagList:     request.Article.TagList,
+		Author:      user.Username,
+		AuthorID:    user.ID,
+	}
+	status := request.Article.Status
+	if status == "" {
+		status = model.ArticlePublished
+	}
+	if errs := setStatus(&article, status, request.Article.PublishAt); len(errs) > 0 {
+		HandleFieldErrors(w, errs, http.StatusUnprocessableEntity)
+		return
+	}
+	created, err := h.ArticleRepository.CreateArticle(article)
 	if err != nil {
+		if errors.Is(err, repository.ErrSlugUnavailable) {
+			HandleError(w, "Could not allocate a unique slug, please retry", http.StatusConflict)
+			return
+		}
 		h.log.With("op", op).Error("Failed to create article", "error", err)
 		http.Error(w, "Failed to create article", http.StatusUnprocessableEntity)
 		return
 	}
 
-	responseWithAuthorUsername := model.DBArticleResponseWithAuthorUsername{
-		Slug:           slug,
-		Title:          article.Title,
-		Description:    article.Description,
-		Body:           article.Body,
-		TagList:        article.TagList,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
-		Favorited:      false,
-		FavoritesCount: 0,
-		Author:         model.AuthorUsername{Username: user.Username},
+	if wantsBodyHTML(r) {
+		created.BodyHTML = markdown.Render(created.Body)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-
-	jsonWithoutCount := model.DBArticleResponseWithUsernameJsonWithoutCount{Articles: responseWithAuthorUsername}
-	err = json.NewEncoder(w).Encode(&jsonWithoutCount)
-	if err != nil {
+	if err := writeArticle(w, http.StatusCreated, created, articleAuthor(created)); err != nil {
 		h.log.With("op", op).Error("Failed to encode response", "error", err)
-		// Note: Cannot send http.Error here as headers are already written
 		return
 	}
-	h.log.With("op", op, "slug", slug).Info("Article created successfully")
+	h.log.With("op", op, "slug", created.Slug).Info("Article created successfully")
 	return
 }
 
@@ -91,38 +96,41 @@ func (h *Handlers) GetArticleHandler(w http.ResponseWriter, r *http.Request) {
 
 	h.log.With("op", op).Info("Attempting to get articles", "author", valueAuthor, "tag", valueTag)
 
-	var articles []model.DBArticleResponseWithAuthorUsername
-	var err error
-	if valueAuthor == "" && valueTag == "" {
-		articles, err = h.ArticleRepository.GetArticles()
+	// Listings are public; signed-in callers don't see authors they muted
+	// or blocked.
+	viewerID, _ := r.Context().Value("uid").(string)
+	articles, err := h.ArticleRepository.ListArticles(model.ArticleFilter{
+		Author:   valueAuthor,
+		Tag:      valueTag,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		h.log.With("op", op).Error("Failed to get articles", "author", valueAuthor, "tag", valueTag, "error", err)
+		http.Error(w, "Failed to get articles", http.StatusInternalServerError)
+		return
+	}
+	authors := make([]*model.Author, len(articles))
+	for i := range articles {
+		authors[i] = &articles[i].Author
+	}
+	if err := h.setFollowing(viewerID, authors); err != nil {
+		h.log.With("op", op).Error("Failed to get follow states", "error", err)
+		HandleError(w, "Failed to get articles", http.StatusInternalServerError)
+		return
+	}
+	if wantsBodyHTML(r) && len(articles) > 0 {
+		bodies := make(map[string]string, len(articles))
+		for _, article := range articles {
+			bodies[article.Slug] = article.Body
+		}
+		rendered, err := h.renderBodies(op, bodies)
 		if err != nil {
-			h.log.With("op", op).Error("Failed to get articles", "error", err)
-			// Removing fmt.Println(err) as we now log the error
-			http.Error(w, "Failed to get articles", http.StatusInternalServerError)
+			h.log.With("op", op).Error("Failed to render articles", "error", err)
+			HandleError(w, "Failed to get articles", http.StatusInternalServerError)
 			return
 		}
-	} else {
-		if valueAuthor != "" && valueTag != "" {
-			articles, err = h.ArticleRepository.GetArticlesByAuthorAndTag(valueAuthor, valueTag)
-			if err != nil {
-				h.log.With("op", op).Error("Failed to get articles by author and tag", "author", valueAuthor, "tag", valueTag, "error", err)
-				http.Error(w, "Failed to get articles by author and tag", http.StatusInternalServerError)
-				return
-			}
-		} else if valueAuthor != "" {
-			articles, err = h.ArticleRepository.GetArticlesByAuthor(valueAuthor)
-			if err != nil {
-				h.log.With("op", op).Error("Failed to get articles by author", "author", valueAuthor, "error", err)
-				http.Error(w, "Failed to get articles by author", http.StatusInternalServerError)
-				return
-			}
-		} else if valueTag != "" {
-			articles, err = h.ArticleRepository.GetArticlesByTag(valueTag)
-			if err != nil {
-				h.log.With("op", op).Error("Failed to get articles by tag", "tag", valueTag, "error", err)
-				http.Error(w, "Failed to get articles by tag", http.StatusInternalServerError)
-				return
-			}
+		for i := range articles {
+			articles[i].BodyHTML = rendered[articles[i].Slug]
 		}
 	}
 	responseJSON := model.DBArticleResponseWithUsernameJson{
@@ -140,3 +148,470 @@ func (h *Handlers) GetArticleHandler(w http.ResponseWriter, r *http.Request) {
 	h.log.With("op", op, "count", len(articles)).Info("Articles retrieved successfully")
 	return
 }
+
+// FeedArticlesHandler lists the published articles by the authors the
+// caller follows, newest first, one page at a time.
+func (h *Handlers) FeedArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.FeedArticlesHandler"
+
+	errs := map[string][]string{}
+	limit, offset := parsePage(r.URL.Query(), errs)
+	if len(errs) > 0 {
+		HandleFieldErrors(w, errs, http.StatusUnprocessableEntity)
+		return
+	}
+
+	uid := r.Context().Value("uid").(string)
+	articles, total, err := h.ArticleRepository.FeedArticles(uid, limit, offset)
+	if err != nil {
+		h.log.With("op", op).Error("Failed to get feed", "uid", uid, "error", err)
+		HandleError(w, "Failed to get feed", http.StatusInternalServerError)
+		return
+	}
+	// Every author in the feed is one the caller follows.
+	for i := range articles {
+		articles[i].Author.Following = true
+	}
+	if wantsBodyHTML(r) && len(articles) > 0 {
+		bodies := make(map[string]string, len(articles))
+		for _, article := range articles {
+			bodies[article.Slug] = article.Body
+		}
+		rendered, err := h.renderBodies(op, bodies)
+		if err != nil {
+			h.log.With("op", op).Error("Failed to render articles", "error", err)
+			HandleError(w, "Failed to get feed", http.StatusInternalServerError)
+			return
+		}
+		for i := range articles {
+			articles[i].BodyHTML = rendered[articles[i].Slug]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(model.DBArticleResponseWithUsernameJson{Articles: articles, ArticlesCount: total})
+	if err != nil {
+		h.log.With("op", op).Error("Failed to encode response", "error", err)
+		return
+	}
+	h.log.With("op", op, "count", len(articles), "total", total).Info("Feed retrieved successfully")
+	return
+}
+
+// SearchArticlesHandler runs a full-text search over the articles a plain
+// listing would show, optionally narrowed by author and tag.
+func (h *Handlers) SearchArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.SearchArticlesHandler"
+
+	q := r.URL.Query()
+	errs := map[string][]string{}
+	text := strings.TrimSpace(q.Get("q"))
+	if text == "" {
+		errs["q"] = append(errs["q"], "can't be blank")
+	}
+	limit, offset := parsePage(q, errs)
+	if len(errs) > 0 {
+		HandleFieldErrors(w, errs, http.StatusUnprocessableEntity)
+		return
+	}
+
+	viewerID, _ := r.Context().Value("uid").(string)
+	filter := model.ArticleFilter{
+		Author:   q.Get("author"),
+		Tag:      q.Get("tag"),
+		ViewerID: viewerID,
+	}
+	results, total, err := h.ArticleRepository.SearchArticles(text, filter, limit, offset)
+	if err != nil {
+		h.log.With("op", op).Error("Failed to search articles", "q", text, "error", err)
+		HandleError(w, "Failed to search articles", http.StatusInternalServerError)
+		return
+	}
+	authors := make([]*model.Author, len(results))
+	for i := range results {
+		authors[i] = &results[i].Author
+	}
+	if err := h.setFollowing(viewerID, authors); err != nil {
+		h.log.With("op", op).Error("Failed to get follow states", "error", err)
+		HandleError(w, "Failed to search articles", http.StatusInternalServerError)
+		return
+	}
+	if wantsBodyHTML(r) && len(results) > 0 {
+		bodies := make(map[string]string, len(results))
+		for _, result := range results {
+			bodies[result.Slug] = result.Body
+		}
+		rendered, err := h.renderBodies(op, bodies)
+		if err != nil {
+			h.log.With("op", op).Error("Failed to render articles", "error", err)
+			HandleError(w, "Failed to search articles", http.StatusInternalServerError)
+			return
+		}
+		for i := range results {
+			results[i].BodyHTML = rendered[results[i].Slug]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(model.ArticleSearchResponse{Articles: results, ArticlesCount: total})
+	if err != nil {
+		h.log.With("op", op).Error("Failed to encode response", "error", err)
+		return
+	}
+	h.log.With("op", op, "count", len(results), "total", total).Info("Articles searched successfully")
+	return
+}
+
+// writeArticle sends a single article with author's profile, which carries
+// the viewer's follow state.
+func writeArticle(w http.ResponseWriter, status int, article model.DBArticle, author model.Author) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(model.DBArticleResponseWithUsernameJsonWithoutCount{Articles: model.DBArticleResponseWithAuthorUsername{
+		Slug:           article.Slug,
+		Title:          article.Title,
+		Description:    article.Description,
+		Body:           article.Body,
+		BodyHTML:       article.BodyHTML,
+		TagList:        article.TagList,
+		CreatedAt:      article.CreatedAt,
+		UpdatedAt:      article.UpdatedAt,
+		FavoritesCount: article.FavoritesCount,
+		Status:         article.Status,
+		PublishAt:      article.PublishAt,
+		Author:         author,
+	}})
+}
+
+// articleAuthor is the profile of article's author as followed by no one.
+func articleAuthor(article model.DBArticle) model.Author {
+	return model.Author{
+		ID:       article.AuthorID,
+		Username: article.Author,
+		Bio:      article.AuthorBio,
+		Image:    article.AuthorImage,
+	}
+}
+
+// setFollowing fills in viewerID's follow state towards authors with one
+// query for all of them. Signed-out viewers follow no one.
+func (h *Handlers) setFollowing(viewerID string, authors []*model.Author) error {
+	if viewerID == "" || len(authors) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(authors))
+	ids := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if !seen[author.ID] {
+			seen[author.ID] = true
+			ids = append(ids, author.ID)
+		}
+	}
+	states, err := h.UserRepository.GetFollowStates(viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for _, author := range authors {
+		author.SetFollowState(states[author.ID])
+	}
+	return nil
+}
+
+// wantsBodyHTML reports whether the request asks for the rendered body with
+// ?bodyHtml=true.
+func wantsBodyHTML(r *http.Request) bool {
+	want, _ := strconv.ParseBool(r.URL.Query().Get("bodyHtml"))
+	return want
+}
+
+// renderBodies returns the sanitized HTML for the bodies of the articles in
+// bodies, keyed by slug like its argument. Each rendering is cached with the
+// article's latest revision and only redone when the revision changes or the
+// renderer version is bumped; failing to cache is logged, not fatal.
+func (h *Handlers) renderBodies(op string, bodies map[string]string) (map[string]string, error) {
+	slugs := make([]string, 0, len(bodies))
+	for slug := range bodies {
+		slugs = append(slugs, slug)
+	}
+	cached, err := h.ArticleRepository.RenderedBodies(slugs)
+	if err != nil {
+		return nil, err
+	}
+	rendered := make(map[string]string, len(bodies))
+	for slug, body := range bodies {
+		c, ok := cached[slug]
+		switch {
+		case !ok:
+			rendered[slug] = markdown.Render(body)
+		case c.Version == markdown.Version:
+			rendered[slug] = c.HTML
+		default:
+			rendered[slug] = markdown.Render(c.Body)
+			if err := h.ArticleRepository.StoreRenderedBody(slug, c.Revision, rendered[slug], markdown.Version); err != nil {
+				h.log.With("op", op, "slug", slug).Warn("Failed to cache rendered body", "error", err)
+			}
+		}
+	}
+	return rendered, nil
+}
+
+// trimBody drops blank lines around an article body and trailing spaces, so
+// a body of only whitespace counts as missing. Leading spaces on the first
+// line are kept: in Markdown they can start a code block.
+func trimBody(body string) string {
+	body = strings.TrimRight(body, " \t\r\n")
+	for {
+		line, rest, found := strings.Cut(body, "\n")
+		if !found || strings.TrimSpace(line) != "" {
+			break
+		}
+		body = rest
+	}
+	if strings.TrimSpace(body) == "" {
+		return ""
+	}
+	return body
+}
+
+// setStatus validates a status change and applies it to article. A publish
+// time is only kept for scheduled articles, where it must be in the future.
+func setStatus(article *model.DBArticle, status string, publishAt *time.Time) map[string][]string {
+	errs := map[string][]string{}
+	if !slices.Contains(model.ArticleStatuses, status) {
+		errs["status"] = append(errs["status"], "must be one of "+strings.Join(model.ArticleStatuses, ", "))
+		return errs
+	}
+	if status != model.ArticleScheduled {
+		publishAt = nil
+	} else if publishAt == nil {
+		errs["publishAt"] = append(errs["publishAt"], "is required for scheduled articles")
+	} else if !publishAt.After(time.Now()) {
+		errs["publishAt"] = append(errs["publishAt"], "must be in the future")
+	}
+	article.Status = status
+	article.PublishAt = publishAt
+	return errs
+}
+
+// visibleTo reports whether viewerID may open article: drafts and scheduled
+// articles are only shown to their author.
+func visibleTo(article model.DBArticle, viewerID string) bool {
+	switch article.Status {
+	case model.ArticleDraft, model.ArticleScheduled:
+		return article.AuthorID == viewerID
+	}
+	return true
+}
+
+// ownArticle loads the article named in the URL and checks that the caller
+// wrote it, answering the request itself when not. Editing and the revision
+// history are limited to the author.
+func (h *Handlers) ownArticle(w http.ResponseWriter, r *http.Request, op string) (model.DBArticle, bool) {
+	slug := mux.Vars(r)["slug"]
+	article, err := h.ArticleRepository.GetArticleBySlug(slug)
+	if err != nil {
+		if errors.Is(err, repository.ErrArticleNotFound) {
+			HandleError(w, "Article not found", http.StatusNotFound)
+			return model.DBArticle{}, false
+		}
+		h.log.With("op", op, "slug", slug).Error("Failed to get article", "error", err)
+		HandleError(w, "Failed to get article", http.StatusInternalServerError)
+		return model.DBArticle{}, false
+	}
+	if article.AuthorID != r.Context().Value("uid").(string) {
+		HandleError(w, "Only the author can manage this article", http.StatusForbidden)
+		return model.DBArticle{}, false
+	}
+	return article, true
+}
+
+// GetSingleArticleHandler returns one article. Slugs the article had before
+// a title change answer with a permanent redirect to the current one. Drafts
+// and scheduled articles look missing to everyone but their author.
+func (h *Handlers) GetSingleArticleHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.GetSingleArticleHandler"
+
+	slug := mux.Vars(r)["slug"]
+	viewerID, _ := r.Context().Value("uid").(string)
+	article, err := h.ArticleRepository.GetArticleBySlug(slug)
+	redirect := false
+	if errors.Is(err, repository.ErrArticleNotFound) {
+		var current string
+		current, err = h.ArticleRepository.ResolveFormerSlug(slug)
+		if err == nil {
+			// The redirect names the current slug, so it is only given to
+			// those who may see the article.
+			article, err = h.ArticleRepository.GetArticleBySlug(current)
+			redirect = true
+		}
+	}
+	if errors.Is(err, repository.ErrArticleNotFound) {
+		HandleError(w, "Article not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.log.With("op", op, "slug", slug).Error("Failed to get article", "error", err)
+		HandleError(w, "Failed to get article", http.StatusInternalServerError)
+		return
+	}
+	if !visibleTo(article, viewerID) {
+		HandleError(w, "Article not found", http.StatusNotFound)
+		return
+	}
+	if redirect {
+		http.Redirect(w, r, "/articles/"+article.Slug, http.StatusMovedPermanently)
+		return
+	}
+	if wantsBodyHTML(r) {
+		rendered, err := h.renderBodies(op, map[string]string{article.Slug: article.Body})
+		if err != nil {
+			h.log.With("op", op, "slug", slug).Error("Failed to render article", "error", err)
+			HandleError(w, "Failed to get article", http.StatusInternalServerError)
+			return
+		}
+		article.BodyHTML = rendered[article.Slug]
+	}
+
+	author := articleAuthor(article)
+	if err := h.setFollowing(viewerID, []*model.Author{&author}); err != nil {
+		h.log.With("op", op, "slug", slug).Error("Failed to get follow state", "error", err)
+		HandleError(w, "Failed to get article", http.StatusInternalServerError)
+		return
+	}
+	if err := writeArticle(w, http.StatusOK, article, author); err != nil {
+		h.log.With("op", op).Error("Failed to encode response", "error", err)
+	}
+	return
+}
+
+// UpdateArticleHandler lets the author edit an article. A new title that
+// slugifies differently moves the article to a new slug; the old one keeps
+// redirecting.
+func (h *Handlers) UpdateArticleHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.UpdateArticleHandler"
+
+	article, ok := h.ownArticle(w, r, op)
+	if !ok {
+		return
+	}
+	request := model.UpdateArticleRequest{}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		h.log.With("op", op).Error("Failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Failed to decode request body")
+		return
+	}
+	if request.Article.Title != nil {
+		*request.Article.Title = strings.TrimSpace(*request.Article.Title)
+	}
+	if request.Article.Description != nil {
+		*request.Article.Description = strings.TrimSpace(*request.Article.Description)
+	}
+	if request.Article.Body != nil {
+		*request.Article.Body = trimBody(*request.Article.Body)
+	}
+	if request.Article.TagList != nil {
+		*request.Article.TagList = model.NormalizeTags(*request.Article.TagList)
+	}
+	err = h.V.Struct(request)
+	if err != nil {
+		h.log.With("op", op).Warn("Article validation failed", "error", err)
+		HandleFieldErrors(w, fieldErrors(err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	edit := model.ArticleEdit{EditorID: r.Context().Value("uid").(string)}
+	if request.Article.Title != nil {
+		edit.NewBase = retitle(&article, *request.Article.Title)
+	}
+	if request.Article.Description != nil {
+		article.Description = *request.Article.Description
+	}
+	if request.Article.Body != nil {
+		article.Body = *request.Article.Body
+	}
+	if request.Article.TagList != nil {
+		article.TagList = *request.Article.TagList
+	}
+	if request.Article.Status != nil || request.Article.PublishAt != nil {
+		status, publishAt := article.Status, article.PublishAt
+		if request.Article.Status != nil {
+			status = *request.Article.Status
+		}
+		if request.Article.PublishAt != nil {
+			publishAt = request.Article.PublishAt
+		}
+		if errs := setStatus(&article, status, publishAt); len(errs) > 0 {
+			HandleFieldErrors(w, errs, http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	h.saveArticle(w, r, op, article, edit)
+	return
+}
+
+// retitle sets article's title and returns the slug base to move the article
+// to, or "" when the new title slugifies like the old one.
+func retitle(article *model.DBArticle, title string) string {
+	newBase := ""
+	if base := pkg.SlugBase(title); base != pkg.SlugBase(article.Title) {
+		newBase = base
+	}
+	article.Title = title
+	return newBase
+}
+
+// saveArticle stores an edited article as a new revision and sends it back.
+func (h *Handlers) saveArticle(w http.ResponseWriter, r *http.Request, op string, article model.DBArticle, edit model.ArticleEdit) {
+	oldSlug := article.Slug
+	article, err := h.ArticleRepository.UpdateArticle(oldSlug, article, edit)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrArticleNotFound):
+			HandleError(w, "Article not found", http.StatusNotFound)
+		case errors.Is(err, repository.ErrSlugUnavailable):
+			HandleError(w, "Could not allocate a unique slug, please retry", http.StatusConflict)
+		default:
+			h.log.With("op", op, "slug", oldSlug).Error("Failed to update article", "error", err)
+			HandleError(w, "Failed to update article", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if wantsBodyHTML(r) {
+		article.BodyHTML = markdown.Render(article.Body)
+	}
+	if err := writeArticle(w, http.StatusOK, article, articleAuthor(article)); err != nil {
+		h.log.With("op", op).Error("Failed to encode response", "error", err)
+		return
+	}
+	h.log.With("op", op, "slug", article.Slug, "previousSlug", oldSlug).Info("Article updated successfully")
+}
+
+// DeleteArticleHandler lets the author delete an article together with its
+// slug history.
+func (h *Handlers) DeleteArticleHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.DeleteArticleHandler"
+
+	article, ok := h.ownArticle(w, r, op)
+	if !ok {
+		return
+	}
+	err := h.ArticleRepository.DeleteArticle(article.Slug)
+	if err != nil {
+		if errors.Is(err, repository.ErrArticleNotFound) {
+			HandleError(w, "Article not found", http.StatusNotFound)
+			return
+		}
+		h.log.With("op", op, "slug", article.Slug).Error("Failed to delete article", "error", err)
+		HandleError(w, "Failed to delete article", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.log.With("op", op, "slug", article.Slug).Info("Article deleted successfully")
+	return
+}
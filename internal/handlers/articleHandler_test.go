@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"rwa/internal/model"
+)
+
+func TestSetStatus(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name          string
+		status        string
+		publishAt     *time.Time
+		wantErrs      []string
+		wantPublishAt *time.Time
+	}{
+		{name: "published", status: model.ArticlePublished},
+		{name: "draft", status: model.ArticleDraft},
+		{name: "unlisted", status: model.ArticleUnlisted},
+		{name: "publish time dropped unless scheduled", status: model.ArticlePublished, publishAt: &future},
+		{name: "scheduled", status: model.ArticleScheduled, publishAt: &future, wantPublishAt: &future},
+		{name: "scheduled without time", status: model.ArticleScheduled, wantErrs: []string{"publishAt"}},
+		{name: "scheduled in the past", status: model.ArticleScheduled, publishAt: &past, wantErrs: []string{"publishAt"}, wantPublishAt: &past},
+		{name: "unknown status", status: "archived", publishAt: &future, wantErrs: []string{"status"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article := model.DBArticle{Status: "before"}
+			errs := setStatus(&article, tt.status, tt.publishAt)
+
+			var keys []string
+			for k := range errs {
+				keys = append(keys, k)
+			}
+			if !reflect.DeepEqual(keys, tt.wantErrs) {
+				t.Errorf("errors = %v, want keys %v", errs, tt.wantErrs)
+			}
+			if tt.status == "archived" {
+				if article.Status != "before" {
+					t.Errorf("invalid status was applied: %q", article.Status)
+				}
+				return
+			}
+			if article.Status != tt.status {
+				t.Errorf("status = %q, want %q", article.Status, tt.status)
+			}
+			if article.PublishAt != tt.wantPublishAt {
+				t.Errorf("publishAt = %v, want %v", article.PublishAt, tt.wantPublishAt)
+			}
+		})
+	}
+}
+
+func TestVisibleTo(t *testing.T) {
+	const author, other = "author-id", "other-id"
+	tests := []struct {
+		status string
+		viewer string
+		want   bool
+	}{
+		{model.ArticlePublished, "", true},
+		{model.ArticlePublished, other, true},
+		{model.ArticleUnlisted, "", true},
+		{model.ArticleUnlisted, other, true},
+		{model.ArticleDraft, author, true},
+		{model.ArticleDraft, other, false},
+		{model.ArticleDraft, "", false},
+		{model.ArticleScheduled, author, true},
+		{model.ArticleScheduled, other, false},
+		{model.ArticleScheduled, "", false},
+	}
+	for _, tt := range tests {
+		article := model.DBArticle{Status: tt.status, AuthorID: author}
+		if got := visibleTo(article, tt.viewer); got != tt.want {
+			t.Errorf("visibleTo(%s, %q) = %v, want %v", tt.status, tt.viewer, got, tt.want)
+		}
+	}
+}
+
+func TestTrimBody(t *testing.T) {
+	cases := map[string]string{
+		"":                        "",
+		" \n\t\r\n ":              "",
+		"text":                    "text",
+		"text  \n\n":              "text",
+		"\n\n  \nfirst\nsecond":   "first\nsecond",
+		"\n    indented code\n":   "    indented code",
+		"  leading spaces kept":   "  leading spaces kept",
+		"line one\n\nline three ": "line one\n\nline three",
+	}
+	for in, want := range cases {
+		if got := trimBody(in); got != want {
+			t.Errorf("trimBody(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSetFollowing(t *testing.T) {
+	// Signed-out viewers follow no one and never reach the database; the
+	// query for signed-in viewers is covered by TestGetFollowStates.
+	h := &Handlers{}
+	author := model.Author{ID: "a", Following: true, FollowRequested: true}
+	if err := h.setFollowing("", []*model.Author{&author}); err != nil {
+		t.Fatal(err)
+	}
+	if !author.Following || !author.FollowRequested {
+		t.Error("signed-out viewer changed the author's follow state")
+	}
+}
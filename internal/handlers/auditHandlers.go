@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+	"time"
+)
+
+// audit records a security event performed by the authenticated caller. See
+// auditAs.
+func (h *Handlers) audit(w http.ResponseWriter, r *http.Request, action string, targetType string, targetID string, details map[string]any) bool {
+	actorID, _ := r.Context().Value("uid").(string)
+	return h.auditAs(w, r, actorID, action, targetType, targetID, details)
+}
+
+// auditAs records a security event for endpoints without an authenticated
+// caller; actorID may be "" when the actor is unknown. Callers record before
+// the action takes effect: if the event cannot be stored it answers 500 and
+// returns false, and the action must not happen. Actions that create their
+// target or can still be refused by the database pass auditEvent to the
+// repository instead, which stores it in the action's transaction.
+func (h *Handlers) auditAs(w http.ResponseWriter, r *http.Request, actorID string, action string, targetType string, targetID string, details map[string]any) bool {
+	const op = "handler.audit"
+
+	if err := h.AuditRepository.Record(h.auditEvent(r, actorID, action, targetType, targetID, details)); err != nil {
+		h.log.Error("failed to record audit event", "op", op, "action", action, "error", err)
+		HandleError(w, "Failed to record audit event", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// auditFailure records a refused attempt, such as a failed login. The refusal
+// stands either way, so an event that cannot be stored is logged in full
+// instead of failing the request.
+func (h *Handlers) auditFailure(r *http.Request, actorID string, action string, targetType string, targetID string, details map[string]any) {
+	const op = "handler.auditFailure"
+
+	event := h.auditEvent(r, actorID, action, targetType, targetID, details)
+	if err := h.AuditRepository.Record(event); err != nil {
+		h.log.Error("failed to record audit event", "op", op, "action", action, "targetType", targetType, "targetID", targetID, "ip", event.IP, "details", details, "error", err)
+	}
+}
+
+// auditEvent describes a security event of r; actorID may be "".
+func (h *Handlers) auditEvent(r *http.Request, actorID string, action string, targetType string, targetID string, details map[string]any) model.AuditEvent {
+	event := model.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         h.clientIP(r),
+		UserAgent:  r.UserAgent(),
+		Details:    details,
+	}
+	if actorID != "" {
+		event.ActorID = &actorID
+	}
+	return event
+}
+
+// parseAuditFilter reads the filters shared by the audit endpoints.
+func parseAuditFilter(q url.Values, errs map[string][]string) model.AuditFilter {
+	limit, offset := parsePage(q, errs)
+	filter := model.AuditFilter{
+		Action: q.Get("action"),
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				errs[p.name] = append(errs[p.name], "must be an RFC 3339 timestamp")
+			}
+			*p.dst = t
+		}
+	}
+	return filter
+}
+
+func (h *Handlers) writeAuditEvents(w http.ResponseWriter, op string, filter model.AuditFilter) {
+	events, total, err := h.AuditRepository.List(filter)
+	if err != nil {
+		h.log.Error(op+": failed to list audit events", "error", err)
+		HandleError(w, "Failed to list audit events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.AuditEventsResponse{Events: events, EventsCount: total})
+}
+
+// MyAuditHandler shows the caller their own security history: what they did,
+// and anonymous events against their account such as failed logins.
+func (h *Handlers) MyAuditHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.MyAuditHandler"
+
+	errs := map[string][]string{}
+	filter := parseAuditFilter(r.URL.Query(), errs)
+	if len(errs) > 0 {
+		HandleFieldErrors(w, errs, http.StatusUnprocessableEntity)
+		return
+	}
+	filter.Subject = r.Context().Value("uid").(string)
+
+	h.writeAuditEvents(w, op, filter)
+	return
+}
+
+// AdminAuditHandler queries the whole audit log. Besides the common filters
+// it accepts actor (a username), target and ip.
+func (h *Handlers) AdminAuditHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminAuditHandler"
+
+	q := r.URL.Query()
+	errs := map[string][]string{}
+	filter := parseAuditFilter(q, errs)
+	filter.TargetID = q.Get("target")
+	filter.IP = q.Get("ip")
+	if len(errs) > 0 {
+		HandleFieldErrors(w, errs, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if actor := q.Get("actor"); actor != "" {
+		user, err := h.UserRepository.GetUserForAuth("", actor)
+		if err != nil {
+			if errors.Is(err, repository.ErrUserNotFound) {
+				HandleFieldErrors(w, map[string][]string{"actor": {"is not a known user"}}, http.StatusUnprocessableEntity)
+				return
+			}
+			h.log.Error(op+": failed to get actor", "error", err, "username", actor)
+			HandleError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		filter.ActorID = user.ID
+	}
+
+	h.writeAuditEvents(w, op, filter)
+	return
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestAuditWithoutStorage checks that an action whose audit event cannot be
+// written is refused with 500 before it takes effect.
+func TestAuditWithoutStorage(t *testing.T) {
+	// Nothing listens on port 1, so every write fails.
+	pool, err := pgxpool.New(context.Background(), "postgres://conduit@127.0.0.1:1/conduit?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	h := &Handlers{AuditRepository: repository.NewPostgresAuditStorage(pool, testLog), log: testLog}
+	r := httptest.NewRequest(http.MethodPost, "/users/login", nil)
+
+	rec := httptest.NewRecorder()
+	if h.auditAs(rec, r, "", model.AuditLoginSucceeded, model.AuditTargetUser, "uid", nil) {
+		t.Error("auditAs reported success without storage")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("auditAs status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"rwa/internal/mailer"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+	"rwa/internal/security"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type RequestConfirmEmail struct {
+	User struct {
+		Token string `json:"token" validate:"required"`
+	} `json:"user"`
+}
+
+// sendEmailVerification issues a fresh verification token for the user's
+// current address and mails it.
+func (h *Handlers) sendEmailVerification(ctx context.Context, uid string, email string) error {
+	token, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(h.cfg.EmailVerificationTTL)
+	err = h.UserRepository.CreateEmailVerification(uid, email, security.HashOpaqueToken(token), expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your Conduit email address",
+		Body:    fmt.Sprintf("Use this token to confirm your email address: %s\nIt expires at %s.", token, expiresAt.Format(time.RFC1123)),
+	})
+}
+
+func (h *Handlers) ConfirmEmailHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ConfirmEmailHandler"
+
+	payload := RequestConfirmEmail{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(payload)
+	if err != nil {
+		h.log.Error(op+": validation failed", "error", err)
+		HandleError(w, err.(validator.ValidationErrors).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	uid, err := h.UserRepository.ConsumeEmailVerification(security.HashOpaqueToken(payload.User.Token),
+		h.auditEvent(r, "", model.AuditEmailVerified, model.AuditTargetUser, "", nil))
+	if err != nil {
+		if errors.Is(err, repository.ErrVerificationInvalid) {
+			HandleError(w, "Verification token is invalid or expired", http.StatusUnprocessableEntity)
+			return
+		}
+		h.log.Error(op+": failed to verify email", "error", err)
+		HandleError(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	h.log.Info(op+": email verified", "uid", uid)
+	return
+}
+
+func (h *Handlers) ResendEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ResendEmailVerificationHandler"
+
+	uid := r.Context().Value("uid").(string)
+	user, err := h.UserRepository.GetUserForApi(uid)
+	if err != nil {
+		h.log.Error(op+": failed to get user", "error", err, "uid", uid)
+		HandleError(w, "Failed to retrieve user data", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if user.EmailVerified {
+		HandleError(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+
+	err = h.sendEmailVerification(r.Context(), user.ID, user.Email)
+	if err != nil {
+		h.log.Error(op+": failed to send verification", "error", err, "uid", uid)
+		HandleError(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	return
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+)
+
+// ListFollowRequestsHandler lists pending requests to follow the caller.
+func (h *Handlers) ListFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ListFollowRequestsHandler"
+
+	uid := r.Context().Value("uid").(string)
+	requests, err := h.UserRepository.ListFollowRequests(uid)
+	if err != nil {
+		h.log.Error(op+": failed to list follow requests", "error", err, "uid", uid)
+		HandleError(w, "Failed to list follow requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.FollowRequestsResponse{Requests: requests})
+	return
+}
+
+func (h *Handlers) ApproveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ApproveFollowRequestHandler"
+
+	requester, ok := h.relationshipTarget(w, r, op)
+	if !ok {
+		return
+	}
+	uid := r.Context().Value("uid").(string)
+
+	err := h.UserRepository.ApproveFollowRequest(uid, requester.ID,
+		h.auditEvent(r, uid, model.AuditFollowRequestApproved, model.AuditTargetUser, requester.ID, nil))
+	if err != nil {
+		if errors.Is(err, repository.ErrFollowRequestNotFound) {
+			HandleError(w, "Follow request not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error(op+": failed to approve follow request", "error", err, "uid", uid, "requester", requester.ID)
+		HandleError(w, "Failed to approve follow request", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRelationshipProfile(w, op, uid, requester)
+	return
+}
+
+func (h *Handlers) RejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.RejectFollowRequestHandler"
+
+	requester, ok := h.relationshipTarget(w, r, op)
+	if !ok {
+		return
+	}
+	uid := r.Context().Value("uid").(string)
+
+	err := h.UserRepository.RejectFollowRequest(uid, requester.ID,
+		h.auditEvent(r, uid, model.AuditFollowRequestRejected, model.AuditTargetUser, requester.ID, nil))
+	if err != nil {
+		if errors.Is(err, repository.ErrFollowRequestNotFound) {
+			HandleError(w, "Follow request not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error(op+": failed to reject follow request", "error", err, "uid", uid, "requester", requester.ID)
+		HandleError(w, "Failed to reject follow request", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.log.Info(op+": follow request rejected", "uid", uid, "requester", requester.ID)
+	return
+}
@@ -2,28 +2,62 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"reflect"
+	"rwa/internal/config"
+	"rwa/internal/mailer"
 	"rwa/internal/model"
+	"rwa/internal/oidc"
 	"rwa/internal/repository"
+	"rwa/internal/security"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Handlers struct {
-	UserRepository    *repository.PostgresUserStorage
-	V                 *validator.Validate
-	ArticleRepository *repository.PostgresArticleStorage
-	log               *slog.Logger
+	UserRepository         *repository.PostgresUserStorage
+	V                      *validator.Validate
+	ArticleRepository      *repository.PostgresArticleStorage
+	LoginAttemptRepository *repository.PostgresLoginAttemptStorage
+	AuditRepository        *repository.PostgresAuditStorage
+	Revocations            *security.RevocationCache
+	Mailer                 mailer.Mailer
+	OIDCProviders          map[string]*oidc.Provider
+	cfg                    config.Config
+	log                    *slog.Logger
 }
 
-func NewHandlers(db *pgxpool.Pool, log *slog.Logger) *Handlers {
+func NewHandlers(db *pgxpool.Pool, log *slog.Logger, cfg config.Config) *Handlers {
+	providers := make(map[string]*oidc.Provider, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil)
+	}
+
 	return &Handlers{
-		UserRepository:    repository.NewPostgresUserStorage(db, log),
-		V:                 validator.New(),
-		ArticleRepository: repository.NewPostgresArticleStorage(db, log),
-		log:               log,
+		UserRepository:         repository.NewPostgresUserStorage(db, log),
+		V:                      newValidator(),
+		ArticleRepository:      repository.NewPostgresArticleStorage(db, log),
+		LoginAttemptRepository: repository.NewPostgresLoginAttemptStorage(db, log),
+		AuditRepository:        repository.NewPostgresAuditStorage(db, log),
+		Revocations:            security.NewRevocationCache(),
+		Mailer:                 mailer.New(cfg.Mailer, cfg.MailFrom, cfg.MailerFile, log),
+		OIDCProviders:          providers,
+		cfg:                    cfg,
+		log:                    log,
 	}
 }
 
@@ -33,3 +67,139 @@ func HandleError(w http.ResponseWriter, errMsg string, statusCode int) {
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(errJson)
 }
+
+// handleDecodeError answers a request whose JSON body could not be decoded:
+// 413 if LimitBody cut it off, 422 with msg otherwise.
+func handleDecodeError(w http.ResponseWriter, err error, msg string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		HandleError(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	HandleError(w, msg, http.StatusUnprocessableEntity)
+}
+
+// newValidator reports fields by their JSON names so validation errors can be
+// keyed the way clients sent them.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("singleline", func(fl validator.FieldLevel) bool {
+		return !strings.ContainsFunc(fl.Field().String(), unicode.IsControl)
+	})
+	v.RegisterValidation("multiline", func(fl validator.FieldLevel) bool {
+		return !strings.ContainsFunc(fl.Field().String(), func(r rune) bool {
+			return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+		})
+	})
+	v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return validUsername(fl.Field().String())
+	})
+	v.RegisterValidation("tag", func(fl validator.FieldLevel) bool {
+		return validTag(fl.Field().String())
+	})
+	v.RegisterAlias("article_title", fmt.Sprintf("max=%d,singleline", model.MaxTitleLength))
+	v.RegisterAlias("article_description", fmt.Sprintf("max=%d,singleline", model.MaxDescriptionLength))
+	v.RegisterAlias("article_body", fmt.Sprintf("max=%d,multiline", model.MaxBodyLength))
+	v.RegisterAlias("article_tags", fmt.Sprintf("max=%d", model.MaxTags))
+	return v
+}
+
+// Username length limits, in bytes since usernames are ASCII.
+const (
+	minUsernameLength = 5
+	maxUsernameLength = 64
+)
+
+// validUsername reports whether name can be a username: ASCII letters,
+// digits and ._- so it is safe in profile URLs.
+func validUsername(name string) bool {
+	if len(name) < minUsernameLength || len(name) > maxUsernameLength {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// validTag reports whether tag is a normalized tag: letters, digits, single
+// inner spaces and the punctuation common in technology names (c++, c#,
+// node.js, ci-cd, snake_case).
+func validTag(tag string) bool {
+	if tag == "" || utf8.RuneCountInString(tag) > model.MaxTagLength || tag != strings.TrimSpace(tag) || strings.Contains(tag, "  ") {
+		return false
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r) && !strings.ContainsRune(" -_.+#", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func HandleFieldErrors(w http.ResponseWriter, errs map[string][]string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(model.FieldErrorJson{Errors: errs})
+}
+
+// fieldErrors turns a validator error into field-keyed messages.
+func fieldErrors(err error) map[string][]string {
+	errs := make(map[string][]string)
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		errs["body"] = append(errs["body"], err.Error())
+		return errs
+	}
+	for _, fe := range verrs {
+		// Errors in list elements are reported under the list:
+		// "tagList", not "tagList[3]".
+		field, _, _ := strings.Cut(fe.Field(), "[")
+		errs[field] = append(errs[field], validationMessage(fe))
+	}
+	return errs
+}
+
+func validationMessage(fe validator.FieldError) string {
+	// ActualTag sees through aliases such as article_title.
+	switch fe.ActualTag() {
+	case "required", "required_without":
+		return "can't be blank"
+	case "email":
+		return "is invalid"
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at least %s items", fe.Param())
+		}
+		if fe.Param() == "1" {
+			return "can't be blank"
+		}
+		return fmt.Sprintf("is too short (minimum is %s characters)", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("is too long (maximum is %s characters)", fe.Param())
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters", fe.Param())
+	case "singleline":
+		return "must be a single line without control characters"
+	case "multiline":
+		return "must not contain control characters"
+	case "username":
+		return fmt.Sprintf("must be %d to %d letters, digits, '.', '_' or '-'", minUsernameLength, maxUsernameLength)
+	case "tag":
+		return fmt.Sprintf("%q must be 1 to %d letters, digits, spaces or -_.+#", fe.Value(), model.MaxTagLength)
+	default:
+		return "is invalid"
+	}
+}
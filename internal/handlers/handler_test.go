@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"rwa/internal/config"
+	"rwa/internal/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var testLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// testHandlers builds handlers on the migrated database in DB_URL, skipping
+// the test when it is not set.
+func testHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	dsn := os.Getenv("DB_URL")
+	if dsn == "" {
+		t.Skip("DB_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return NewHandlers(pool, testLog, config.Config{})
+}
+
+// newTestUser registers a user with a random name, deleting it again when
+// the test ends.
+func newTestUser(t *testing.T, h *Handlers) model.UserTableDB {
+	t.Helper()
+	name := fmt.Sprintf("test_%d", rand.Int63())
+	if err := h.UserRepository.RegisterUser(name, name+"@example.com", "correct horse battery"); err != nil {
+		t.Fatal(err)
+	}
+	user, err := h.UserRepository.GetUserForAuth("", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := h.UserRepository.DeleteAccount(user.ID, model.ArticlesDelete, time.Now()); err != nil {
+			t.Error(err)
+		}
+	})
+	return user
+}
+
+// Validation and password policy errors must share the JSON field names, or
+// a client gets "Password" and "password" for the same field. The validator
+// comes from NewHandlers so the test covers what the server actually uses.
+func TestFieldErrorsUseJSONNames(t *testing.T) {
+	v := NewHandlers(nil, testLog, config.Config{}).V
+
+	register := RequestUser{}
+	register.User.Email = "not an email"
+	errs := fieldErrors(v.Struct(register))
+	for _, key := range []string{"username", "email", "password"} {
+		if len(errs[key]) == 0 {
+			t.Errorf("registration errors = %v, missing %q", errs, key)
+		}
+	}
+
+	reset := RequestConfirmPasswordReset{}
+	errs = fieldErrors(v.Struct(reset))
+	for _, key := range []string{"token", "newPassword"} {
+		if len(errs[key]) == 0 {
+			t.Errorf("reset errors = %v, missing %q", errs, key)
+		}
+	}
+}
+
+func TestValidUsername(t *testing.T) {
+	cases := map[string]bool{
+		"gopher":           true,
+		"go.pher_1-x":      true,
+		"abcd":             false,
+		"gopher with gaps": false,
+		"gopher/../admin":  false,
+		"gøpher":           false,
+		"gopher<script>":   false,
+		"":                 false,
+	}
+	cases[string(make([]byte, 65))] = false
+	long := ""
+	for len(long) < 64 {
+		long += "a"
+	}
+	cases[long] = true
+	cases[long+"a"] = false
+
+	for name, want := range cases {
+		if got := validUsername(name); got != want {
+			t.Errorf("validUsername(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestValidTag(t *testing.T) {
+	cases := map[string]bool{
+		"go":                            true,
+		"c++":                           true,
+		"c#":                            true,
+		"node.js":                       true,
+		"ci-cd":                         true,
+		"snake_case":                    true,
+		"machine learning":              true,
+		"программирование":              true,
+		"café":                          true,
+		"":                              false,
+		" go":                           false,
+		"go ":                           false,
+		"two  spaces":                   false,
+		"a/b":                           false,
+		"<script>":                      false,
+		"tab\there":                     false,
+		strings.Repeat("a", 32):         true,
+		strings.Repeat("a", 33):         false,
+		strings.Repeat("я", 32):         true,
+		"emoji🙂":                        false,
+		"combininǵ mark":               true,
+		"new\nline":                     false,
+		"zero​width":                    false,
+		"nbsp space":                    false,
+		"digits123":                     true,
+		"mixed-Case_1.0+#":              true,
+		strings.Repeat("ab ", 10) + "a": true,
+	}
+	for tag, want := range cases {
+		if got := validTag(tag); got != want {
+			t.Errorf("validTag(%q) = %v, want %v", tag, got, want)
+		}
+	}
+}
+
+// Errors in list elements are reported under the list's name, once per bad
+// element.
+func TestFieldErrorsStripIndexes(t *testing.T) {
+	v := newValidator()
+	req := model.CreateArticleRequest{}
+	req.Article.Title = "Title"
+	req.Article.Body = "Body"
+	req.Article.TagList = []string{"go", "bad/tag", "", "ok"}
+	errs := fieldErrors(v.Struct(req))
+	if len(errs) != 1 || len(errs["tagList"]) != 2 {
+		t.Fatalf("errors = %v, want two under tagList", errs)
+	}
+	if !strings.Contains(errs["tagList"][0], `"bad/tag"`) {
+		t.Errorf("tagList error %q does not name the tag", errs["tagList"][0])
+	}
+}
+
+// The article_* validate tags must enforce exactly the limits in the model.
+func TestArticleLimitsFollowConstants(t *testing.T) {
+	v := newValidator()
+	valid := func() model.CreateArticleRequest {
+		req := model.CreateArticleRequest{}
+		req.Article.Title = strings.Repeat("t", model.MaxTitleLength)
+		req.Article.Description = strings.Repeat("d", model.MaxDescriptionLength)
+		req.Article.Body = strings.Repeat("b", model.MaxBodyLength)
+		for i := 0; i < model.MaxTags; i++ {
+			req.Article.TagList = append(req.Article.TagList, fmt.Sprintf("tag%d", i))
+		}
+		return req
+	}
+	if err := v.Struct(valid()); err != nil {
+		t.Fatalf("request at the limits rejected: %v", fieldErrors(err))
+	}
+
+	over := map[string]func(*model.CreateArticleRequest){
+		"title":       func(r *model.CreateArticleRequest) { r.Article.Title += "t" },
+		"description": func(r *model.CreateArticleRequest) { r.Article.Description += "d" },
+		"body":        func(r *model.CreateArticleRequest) { r.Article.Body += "b" },
+		"tagList":     func(r *model.CreateArticleRequest) { r.Article.TagList = append(r.Article.TagList, "one-more") },
+	}
+	for field, grow := range over {
+		req := valid()
+		grow(&req)
+		errs := fieldErrors(v.Struct(req))
+		if len(errs[field]) != 1 || !strings.Contains(errs[field][0], "at most") && !strings.Contains(errs[field][0], "too long") {
+			t.Errorf("%s over the limit: errors = %v", field, errs)
+		}
+	}
+
+	update := model.UpdateArticleRequest{}
+	title := strings.Repeat("t", model.MaxTitleLength+1)
+	update.Article.Title = &title
+	if errs := fieldErrors(v.Struct(update)); len(errs["title"]) != 1 {
+		t.Errorf("update title over the limit: errors = %v", errs)
+	}
+}
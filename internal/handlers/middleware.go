@@ -2,8 +2,13 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"rwa/internal/model"
+	"rwa/internal/repository"
 	"rwa/internal/security"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -20,32 +25,195 @@ func (h *Handlers) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenString := authHeader[6:] // Get token part
+		tokenString, ok := strings.CutPrefix(authHeader, "Token ")
+		if !ok {
+			tokenString, ok = strings.CutPrefix(authHeader, "Bearer ")
+		}
+		if !ok || tokenString == "" {
+			h.log.Warn("Malformed Authorization header", "op", op)
+			HandleError(writer, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+
+		if strings.HasPrefix(tokenString, security.PersonalTokenPrefix) {
+			pat, err := h.UserRepository.UsePersonalToken(security.HashOpaqueToken(tokenString))
+			if err != nil {
+				h.log.Warn("Personal access token rejected", "op", op, "error", err)
+				HandleError(writer, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			access, ok := h.checkAccess(writer, pat.UID)
+			if !ok {
+				return
+			}
+			ctx := context.WithValue(request.Context(), "uid", pat.UID)
+			ctx = context.WithValue(ctx, "token", tokenString)
+			ctx = context.WithValue(ctx, "scopes", pat.Scopes)
+			ctx = context.WithValue(ctx, "role", access.Role)
+			next.ServeHTTP(writer, request.WithContext(ctx))
+			return
+		}
 
-		uid, err := security.DecodeToken(tokenString)
+		claims, err := security.ParseToken(tokenString)
 		if err != nil {
 			h.log.Error("Failed to decode token", "op", op, "error", err)
 			HandleError(writer, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
 
-		token, err := h.UserRepository.GetToken(tokenString)
-		if err != nil {
-			// Consider if this should be 500 or 401 depending on expected errors
-			h.log.Error("Failed to retrieve token from repository", "op", op, "error", err)
-			// Decide if token not found is a client error (401) or server error (500)
-			HandleError(writer, "Failed to validate token", http.StatusUnauthorized) // Or Internal Server Error 500
+		var role string
+		if h.cfg.StatelessTokens() {
+			// Stateless mode never touches the database: the role comes from
+			// the token, and suspension or a role change revokes it.
+			if h.Revocations.IsRevoked(claims.Jti) {
+				h.log.Warn("Token has been revoked", "op", op, "uid", claims.UID)
+				HandleError(writer, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			if claims.Role == "" {
+				h.log.Warn("Token predates role claims", "op", op, "uid", claims.UID)
+				HandleError(writer, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			role = claims.Role
+		} else {
+			token, err := h.UserRepository.GetToken(tokenString)
+			if err != nil {
+				// Consider if this should be 500 or 401 depending on expected errors
+				h.log.Error("Failed to retrieve token from repository", "op", op, "error", err)
+				// Decide if token not found is a client error (401) or server error (500)
+				HandleError(writer, "Failed to validate token", http.StatusUnauthorized) // Or Internal Server Error 500
+				return
+			}
+
+			if token.UID != claims.UID || !token.EndDate.After(time.Now()) {
+				h.log.Warn("Token validation failed: UID mismatch or token expired", "op", op, "tokenUID", token.UID, "decodedUID", claims.UID, "expiry", token.EndDate)
+				HandleError(writer, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+
+			access, ok := h.checkAccess(writer, claims.UID)
+			if !ok {
+				return
+			}
+			role = access.Role
+		}
+
+		// Use request's context as base
+		ctx := context.WithValue(request.Context(), "uid", claims.UID)
+		ctx = context.WithValue(ctx, "token", tokenString)
+		ctx = context.WithValue(ctx, "role", role)
+		next.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
+// OptionalAuth authenticates requests that carry an Authorization header and
+// lets anonymous requests through, for public endpoints that personalize
+// their response for signed-in callers.
+func (h *Handlers) OptionalAuth(next http.Handler) http.Handler {
+	authenticated := h.AuthMiddleware(next)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") == "" {
+			next.ServeHTTP(writer, request)
 			return
 		}
+		authenticated.ServeHTTP(writer, request)
+	})
+}
+
+// checkAccess loads the role of an authenticated user and rejects suspended
+// accounts. It writes the error response itself and reports whether the
+// request may continue.
+func (h *Handlers) checkAccess(writer http.ResponseWriter, uid string) (model.UserAccess, bool) {
+	const op = "handler.checkAccess"
 
-		if token.UID != uid || !token.EndDate.After(time.Now()) {
-			h.log.Warn("Token validation failed: UID mismatch or token expired", "op", op, "tokenUID", token.UID, "decodedUID", uid, "expiry", token.EndDate)
+	access, err := h.UserRepository.GetUserAccess(uid)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			h.log.Warn("Token belongs to a missing user", "op", op, "uid", uid)
 			HandleError(writer, "Invalid or expired token", http.StatusUnauthorized)
+			return model.UserAccess{}, false
+		}
+		h.log.Error("Failed to load user access", "op", op, "uid", uid, "error", err)
+		HandleError(writer, "Failed to validate token", http.StatusInternalServerError)
+		return model.UserAccess{}, false
+	}
+	if access.SuspendedAt != nil {
+		h.log.Warn("Suspended user rejected", "op", op, "uid", uid)
+		HandleError(writer, "Account is suspended", http.StatusForbidden)
+		return model.UserAccess{}, false
+	}
+	return access, true
+}
+
+// RequirePermission lets the request through only if the caller's role
+// grants permission. It must run inside AuthMiddleware.
+func (h *Handlers) RequirePermission(permission string, next http.Handler) http.Handler {
+	const op = "handler.RequirePermission"
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		role, _ := request.Context().Value("role").(string)
+		if !model.RoleHasPermission(role, permission) {
+			h.log.Warn("Permission denied", "op", op, "permission", permission, "role", role, "path", request.URL.Path)
+			HandleError(writer, "Forbidden", http.StatusForbidden)
 			return
 		}
+		next.ServeHTTP(writer, request)
+	})
+}
 
-		// Use request's context as base
-		ctx := context.WithValue(request.Context(), "uid", token.UID)
-		next.ServeHTTP(writer, request.WithContext(ctx))
+// RequireScope lets personal access tokens through only if they were granted
+// scope. Session tokens carry no scope list and have full access.
+func (h *Handlers) RequireScope(scope string, next http.Handler) http.Handler {
+	const op = "handler.RequireScope"
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		scopes, isPersonal := request.Context().Value("scopes").([]string)
+		if isPersonal && !slices.Contains(scopes, scope) {
+			h.log.Warn("Token lacks scope", "op", op, "scope", scope)
+			HandleError(writer, "Token is missing the "+scope+" scope", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// isPersonalToken reports whether the request was authenticated with a
+// personal access token rather than a session token.
+func isPersonalToken(r *http.Request) bool {
+	_, isPersonal := r.Context().Value("scopes").([]string)
+	return isPersonal
+}
+
+// SessionOnly rejects personal access tokens, for endpoints that manage
+// credentials and must be reached with an interactive login.
+func (h *Handlers) SessionOnly(next http.Handler) http.Handler {
+	const op = "handler.SessionOnly"
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if isPersonalToken(request) {
+			h.log.Warn("Personal access token used for session-only endpoint", "op", op, "path", request.URL.Path)
+			HandleError(writer, "This endpoint requires a session token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// LimitBody caps request bodies at the configured size. Requests announcing
+// a larger body are refused up front; for the rest reading stops at the
+// limit, so decoding an oversized chunked body fails.
+func (h *Handlers) LimitBody(next http.Handler) http.Handler {
+	const op = "handler.LimitBody"
+	limit := int64(h.cfg.MaxRequestBody)
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.ContentLength > limit {
+			h.log.Warn("Request body too large", "op", op, "length", request.ContentLength, "path", request.URL.Path)
+			HandleError(writer, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		request.Body = http.MaxBytesReader(writer, request.Body, limit)
+		next.ServeHTTP(writer, request)
 	})
 }
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rwa/internal/config"
+	"rwa/internal/model"
+	"rwa/internal/security"
+	"strings"
+	"testing"
+)
+
+func TestLimitBody(t *testing.T) {
+	h := &Handlers{
+		cfg: config.Config{MaxRequestBody: 64},
+		log: testLog,
+	}
+	decode := h.LimitBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			handleDecodeError(w, err, "Invalid request payload")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	small := `{"a":"b"}`
+	large := `{"a":"` + strings.Repeat("b", 100) + `"}`
+	cases := []struct {
+		name    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"within limit", small, false, http.StatusNoContent},
+		{"announced too large", large, false, http.StatusRequestEntityTooLarge},
+		{"chunked within limit", small, true, http.StatusNoContent},
+		{"chunked too large", large, true, http.StatusRequestEntityTooLarge},
+		{"malformed", `{"a":`, false, http.StatusUnprocessableEntity},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+			if c.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			decode.ServeHTTP(rec, req)
+			if rec.Code != c.want {
+				t.Errorf("status = %d, want %d", rec.Code, c.want)
+			}
+		})
+	}
+}
+
+// withScopes marks the request as authenticated by a personal access token
+// granted scopes, as AuthMiddleware does.
+func withScopes(r *http.Request, scopes ...string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), "scopes", scopes))
+}
+
+func TestRequireScope(t *testing.T) {
+	h := &Handlers{log: testLog}
+	next := h.RequireScope(model.ScopeArticlesWrite, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"session token", httptest.NewRequest(http.MethodPost, "/articles", nil), http.StatusNoContent},
+		{"granted scope", withScopes(httptest.NewRequest(http.MethodPost, "/articles", nil), model.ScopeArticlesRead, model.ScopeArticlesWrite), http.StatusNoContent},
+		{"other scope", withScopes(httptest.NewRequest(http.MethodPost, "/articles", nil), model.ScopeArticlesRead), http.StatusForbidden},
+		{"no scopes", withScopes(httptest.NewRequest(http.MethodPost, "/articles", nil)), http.StatusForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, c.req)
+			if rec.Code != c.want {
+				t.Errorf("status = %d, want %d", rec.Code, c.want)
+			}
+		})
+	}
+}
+
+func TestSessionOnly(t *testing.T) {
+	h := &Handlers{log: testLog}
+	next := h.SessionOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	next.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/user/tokens", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("session token: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	rec = httptest.NewRecorder()
+	next.ServeHTTP(rec, withScopes(httptest.NewRequest(http.MethodPost, "/user/tokens", nil), model.Scopes...))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("personal token: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+// TestPersonalTokenScopes drives a real token through AuthMiddleware, so the
+// scopes the pat_ branch puts on the request are the ones checked.
+func TestPersonalTokenScopes(t *testing.T) {
+	h := testHandlers(t)
+	user := newTestUser(t, h)
+
+	raw, err := security.GeneratePersonalToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.UserRepository.CreatePersonalToken(user.ID, "test", security.HashOpaqueToken(raw), []string{model.ScopeUserWrite}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	cases := []struct {
+		name    string
+		handler http.Handler
+		body    string
+		want    int
+	}{
+		{"outside its scope", h.AuthMiddleware(h.RequireScope(model.ScopeArticlesWrite, ok)), "", http.StatusForbidden},
+		{"within its scope", h.AuthMiddleware(h.RequireScope(model.ScopeUserWrite, ok)), "", http.StatusNoContent},
+		{"session-only route", h.AuthMiddleware(h.SessionOnly(ok)), "", http.StatusForbidden},
+		{"bio change", h.AuthMiddleware(h.RequireScope(model.ScopeUserWrite, http.HandlerFunc(h.UpdateUserHandler))), `{"user":{"bio":"hello"}}`, http.StatusOK},
+		{"email change", h.AuthMiddleware(h.RequireScope(model.ScopeUserWrite, http.HandlerFunc(h.UpdateUserHandler))), `{"user":{"email":"other_` + user.Email + `","currentPassword":"correct horse battery"}}`, http.StatusForbidden},
+		{"username change", h.AuthMiddleware(h.RequireScope(model.ScopeUserWrite, http.HandlerFunc(h.UpdateUserHandler))), `{"user":{"username":"x` + user.Username + `","currentPassword":"correct horse battery"}}`, http.StatusForbidden},
+		{"privacy change", h.AuthMiddleware(h.RequireScope(model.ScopeUserWrite, http.HandlerFunc(h.UpdateUserHandler))), `{"user":{"private":true}}`, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/users", strings.NewReader(c.body))
+			req.Header.Set("Authorization", "Token "+raw)
+			rec := httptest.NewRecorder()
+			c.handler.ServeHTTP(rec, req)
+			if rec.Code != c.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, c.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	h := &Handlers{log: testLog}
+	next := h.RequirePermission(model.PermissionUsersSuspend, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := map[string]int{
+		model.RoleAdmin:     http.StatusNoContent,
+		model.RoleModerator: http.StatusForbidden,
+		model.RoleUser:      http.StatusForbidden,
+		"":                  http.StatusForbidden,
+	}
+	for role, want := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/someone/suspend", nil)
+		req = req.WithContext(context.WithValue(req.Context(), "role", role))
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("role %q: status = %d, want %d", role, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"rwa/internal/model"
+	"rwa/internal/oidc"
+	"rwa/internal/repository"
+	"rwa/internal/security"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// oidcStateTTL bounds how long a user may spend at the identity provider.
+const oidcStateTTL = 10 * time.Minute
+
+// reauthTokenTTL is how long a re-authentication may be used to confirm an
+// action.
+const reauthTokenTTL = 5 * time.Minute
+
+// reauthClockSkew is tolerated between our clock and the provider's when
+// checking that a re-authentication is fresh.
+const reauthClockSkew = time.Minute
+
+// oidcStateCookie ties a login to the browser that started it. Without it an
+// attacker could start a flow, stop at the callback URL and have a victim's
+// browser finish it, signing the victim into the attacker's account.
+const oidcStateCookie = "oidc_state"
+
+func oidcCookiePath(provider *oidc.Provider) string {
+	return "/auth/oidc/" + provider.Name() + "/"
+}
+
+// setOIDCStateCookie stores the state's hash for the callback to compare.
+// SameSite=Lax still sends it on the provider's top-level redirect back.
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, provider *oidc.Provider, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    security.HashOpaqueToken(state),
+		Path:     oidcCookiePath(provider),
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(provider.RedirectURL(), "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// checkOIDCStateCookie reports whether the callback's state is the one this
+// browser was sent off with, and clears the cookie.
+func checkOIDCStateCookie(w http.ResponseWriter, r *http.Request, provider *oidc.Provider, state string) bool {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     oidcCookiePath(provider),
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(security.HashOpaqueToken(state))) == 1
+}
+
+func (h *Handlers) oidcProvider(w http.ResponseWriter, r *http.Request) *oidc.Provider {
+	name := mux.Vars(r)["provider"]
+	p, ok := h.OIDCProviders[name]
+	if !ok {
+		HandleError(w, "Unknown identity provider", http.StatusNotFound)
+		return nil
+	}
+	return p
+}
+
+// OIDCLoginHandler starts the authorization code flow by redirecting the
+// browser to the identity provider.
+func (h *Handlers) OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	provider := h.oidcProvider(w, r)
+	if provider == nil {
+		return
+	}
+
+	authURL, ok := h.startOIDCFlow(w, r, provider, "", "handlers.OIDCLoginHandler")
+	if !ok {
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// StartOIDCReauthHandler begins a re-authentication of the signed-in user at
+// an identity provider they have linked, for confirming actions that
+// otherwise need the password. The API call carries the session token, so
+// it answers with the URL for the client to open instead of redirecting. A
+// cross-origin client cannot rely on a cookie set here, so the state is
+// bound to the user in storage rather than to the browser.
+func (h *Handlers) StartOIDCReauthHandler(w http.ResponseWriter, r *http.Request) {
+	provider := h.oidcProvider(w, r)
+	if provider == nil {
+		return
+	}
+
+	uid := r.Context().Value("uid").(string)
+	authURL, ok := h.startOIDCFlow(w, r, provider, uid, "handlers.StartOIDCReauthHandler")
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.ReauthStartResponse{URL: authURL})
+}
+
+// startOIDCFlow stores a new login state, binds a login to the browser and
+// returns the provider URL to send the user to. It writes the error response
+// itself.
+func (h *Handlers) startOIDCFlow(w http.ResponseWriter, r *http.Request, provider *oidc.Provider, reauthUID string, op string) (string, bool) {
+	state, err := security.GenerateOpaqueToken()
+	if err != nil {
+		h.log.Error(op+": failed to generate state", "error", err)
+		HandleError(w, "Failed to start login", http.StatusInternalServerError)
+		return "", false
+	}
+	nonce, err := security.GenerateOpaqueToken()
+	if err != nil {
+		h.log.Error(op+": failed to generate nonce", "error", err)
+		HandleError(w, "Failed to start login", http.StatusInternalServerError)
+		return "", false
+	}
+	verifier, err := oidc.GenerateVerifier()
+	if err != nil {
+		h.log.Error(op+": failed to generate verifier", "error", err)
+		HandleError(w, "Failed to start login", http.StatusInternalServerError)
+		return "", false
+	}
+
+	err = h.UserRepository.CreateOIDCState(security.HashOpaqueToken(state), provider.Name(), nonce, verifier, reauthUID, time.Now().Add(oidcStateTTL))
+	if err != nil {
+		h.log.Error(op+": failed to store state", "error", err)
+		HandleError(w, "Failed to start login", http.StatusInternalServerError)
+		return "", false
+	}
+
+	authCodeURL := provider.AuthCodeURL
+	if reauthUID != "" {
+		authCodeURL = provider.ReauthCodeURL
+	}
+	authURL, err := authCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		h.log.Error(op+": failed to build authorization url", "error", err, "provider", provider.Name())
+		HandleError(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return "", false
+	}
+
+	if reauthUID == "" {
+		setOIDCStateCookie(w, r, provider, state)
+	}
+	return authURL, true
+}
+
+func (h *Handlers) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.OIDCCallbackHandler"
+
+	provider := h.oidcProvider(w, r)
+	if provider == nil {
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		h.log.Warn(op+": provider returned error", "provider", provider.Name(), "error", e)
+		HandleError(w, "Login was cancelled or denied", http.StatusUnauthorized)
+		return
+	}
+
+	sameBrowser := checkOIDCStateCookie(w, r, provider, q.Get("state"))
+	state, err := h.UserRepository.ConsumeOIDCState(security.HashOpaqueToken(q.Get("state")), provider.Name())
+	if err != nil {
+		if errors.Is(err, repository.ErrChallengeInvalid) {
+			HandleError(w, "Login state is invalid or expired", http.StatusUnauthorized)
+			return
+		}
+		h.log.Error(op+": failed to consume state", "error", err)
+		HandleError(w, "Failed to complete login", http.StatusInternalServerError)
+		return
+	}
+	// A re-authentication is bound to the user who started it instead, and
+	// only succeeds for an identity linked to that user.
+	if state.ReauthUserID == "" && !sameBrowser {
+		h.log.Warn(op+": state does not match this browser", "provider", provider.Name())
+		HandleError(w, "Login state is invalid or expired", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), q.Get("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		h.log.Error(op+": code exchange failed", "error", err, "provider", provider.Name())
+		HandleError(w, "Failed to complete login", http.StatusUnauthorized)
+		return
+	}
+
+	if state.ReauthUserID != "" {
+		h.completeOIDCReauth(w, r, provider, state, claims)
+		return
+	}
+
+	user, created, err := h.resolveIdentity(r, provider.Name(), claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEmailAlreadyExists):
+			HandleError(w, "An account with this email already exists; log in with your password to continue", http.StatusConflict)
+		case errors.Is(err, errMissingEmail):
+			HandleError(w, "The identity provider did not share an email address", http.StatusUnprocessableEntity)
+		default:
+			h.log.Error(op+": failed to resolve identity", "error", err, "provider", provider.Name())
+			HandleError(w, "Failed to complete login", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if user.SuspendedAt != nil {
+		h.log.Warn(op+": suspended user rejected", "uid", user.ID)
+		HandleError(w, "Account is suspended", http.StatusForbidden)
+		return
+	}
+
+	if user.TOTPEnabled {
+		if !h.auditAs(w, r, user.ID, model.AuditLoginChallenged, model.AuditTargetUser, user.ID, map[string]any{"method": "oidc", "provider": provider.Name()}) {
+			return
+		}
+		h.startLoginChallenge(w, user.ID)
+		return
+	}
+
+	if !h.auditAs(w, r, user.ID, model.AuditLoginSucceeded, model.AuditTargetUser, user.ID, map[string]any{"method": "oidc", "provider": provider.Name()}) {
+		return
+	}
+	token, err := h.sessionToken(user.ID, user.Role)
+	if err != nil {
+		h.log.Error(op+": failed to get session token", "error", err, "uid", user.ID)
+		HandleError(w, "Failed to retrieve authentication token", http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := model.UserResponse{
+		Id:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		Bio:           user.Bio,
+		Image:         user.Image,
+		EmailVerified: user.EmailVerified,
+		Private:       user.Private,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Token:         token,
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(model.UserResponseJSON{User: response})
+	h.log.Info(op+": external login completed", "uid", user.ID, "provider", provider.Name(), "created", created)
+	return
+}
+
+// completeOIDCReauth checks that a re-authentication came from an identity
+// linked to the user who started it and that the provider really prompted
+// again, then hands out a reauth token.
+func (h *Handlers) completeOIDCReauth(w http.ResponseWriter, r *http.Request, provider *oidc.Provider, state model.OIDCState, claims oidc.Claims) {
+	const op = "handlers.completeOIDCReauth"
+
+	uid := state.ReauthUserID
+	user, err := h.UserRepository.GetUserByIdentity(provider.Name(), claims.Subject)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		h.log.Error(op+": failed to look up identity", "error", err, "provider", provider.Name())
+		HandleError(w, "Failed to complete re-authentication", http.StatusInternalServerError)
+		return
+	}
+	if err != nil || user.ID != uid {
+		h.log.Warn(op+": identity is not linked to the user", "uid", uid, "provider", provider.Name())
+		HandleError(w, "This identity is not linked to your account", http.StatusForbidden)
+		return
+	}
+	if !freshAuthentication(claims, state.CreatedAt) {
+		h.log.Warn(op+": provider did not re-authenticate", "uid", uid, "provider", provider.Name(), "authTime", claims.AuthTime)
+		HandleError(w, "The identity provider did not ask you to sign in again", http.StatusUnauthorized)
+		return
+	}
+
+	if !h.auditAs(w, r, uid, model.AuditReauthenticated, model.AuditTargetUser, uid, map[string]any{"provider": provider.Name()}) {
+		return
+	}
+	token, err := security.GenerateOpaqueToken()
+	if err != nil {
+		h.log.Error(op+": failed to generate reauth token", "error", err, "uid", uid)
+		HandleError(w, "Failed to complete re-authentication", http.StatusInternalServerError)
+		return
+	}
+	expiresAt := time.Now().Add(reauthTokenTTL)
+	if err := h.UserRepository.CreateReauthToken(uid, security.HashOpaqueToken(token), expiresAt); err != nil {
+		h.log.Error(op+": failed to store reauth token", "error", err, "uid", uid)
+		HandleError(w, "Failed to complete re-authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.ReauthResponse{ReauthToken: token, ExpiresAt: expiresAt})
+}
+
+// freshAuthentication reports whether the provider authenticated the user
+// after the flow started, allowing for clock skew between us and it.
+func freshAuthentication(claims oidc.Claims, started time.Time) bool {
+	return claims.AuthTime != 0 && !time.Unix(claims.AuthTime, 0).Before(started.Add(-reauthClockSkew))
+}
+
+var errMissingEmail = errors.New("identity has no email")
+
+// maxUsernameAttempts bounds the collision retries for generated usernames.
+const maxUsernameAttempts = 20
+
+// resolveIdentity maps an external identity to a local user: an already
+// linked user, an existing account with the same verified email, or a new
+// account, whose registration is audited with it.
+func (h *Handlers) resolveIdentity(r *http.Request, provider string, claims oidc.Claims) (model.UserTableDB, bool, error) {
+	user, err := h.UserRepository.GetUserByIdentity(provider, claims.Subject)
+	if err == nil {
+		return user, false, nil
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return model.UserTableDB{}, false, err
+	}
+
+	if claims.Email == "" {
+		return model.UserTableDB{}, false, errMissingEmail
+	}
+
+	existing, err := h.UserRepository.GetUserForAuth(claims.Email, "")
+	if err == nil {
+		if !h.cfg.OIDCLinkVerifiedEmail || !claims.EmailVerified || !existing.EmailVerified {
+			return model.UserTableDB{}, false, repository.ErrEmailAlreadyExists
+		}
+		if err := h.UserRepository.LinkIdentity(existing.ID, provider, claims.Subject, claims.Email); err != nil {
+			return model.UserTableDB{}, false, err
+		}
+		return existing, false, nil
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return model.UserTableDB{}, false, err
+	}
+
+	// External accounts get an unusable random password; they can set a
+	// real one through the password reset flow.
+	random, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return model.UserTableDB{}, false, err
+	}
+	passwd, err := security.GeneratePasswd(random)
+	if err != nil {
+		return model.UserTableDB{}, false, err
+	}
+
+	base := usernameBase(claims)
+	for i := 0; i < maxUsernameAttempts; i++ {
+		candidate := base
+		if i > 0 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		user, err = h.UserRepository.CreateUserWithIdentity(model.UserTableDB{
+			Username:      candidate,
+			Email:         claims.Email,
+			PasswordHash:  passwd.Hash,
+			PasswordSalt:  passwd.Salt,
+			EmailVerified: claims.EmailVerified,
+		}, provider, claims.Subject, h.auditEvent(r, "", model.AuditUserRegistered, model.AuditTargetUser, "", map[string]any{"provider": provider}))
+		if errors.Is(err, repository.ErrUsernameAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return model.UserTableDB{}, false, err
+		}
+		return user, true, nil
+	}
+	return model.UserTableDB{}, false, fmt.Errorf("no free username for %q after %d attempts", base, maxUsernameAttempts)
+}
+
+// usernameBase derives a username from the identity's preferred username,
+// display name or email, keeping only characters usernames are made of.
+func usernameBase(claims oidc.Claims) string {
+	source := claims.PreferredUsername
+	if source == "" {
+		source = claims.Name
+	}
+	if source == "" {
+		source, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	var sb strings.Builder
+	for _, r := range strings.ToLower(source) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			sb.WriteRune(r)
+		case r == ' ' || r == '.':
+			sb.WriteRune('_')
+		}
+	}
+	base := strings.Trim(sb.String(), "_-")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if base == "" {
+		return "conduit_user"
+	}
+	if len(base) < 5 {
+		base += "_user"
+	}
+	return base
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"rwa/internal/oidc"
+	"testing"
+	"time"
+)
+
+func TestOIDCStateCookie(t *testing.T) {
+	provider := oidc.NewProvider(oidc.Config{Name: "stub", RedirectURL: "https://app.test/auth/oidc/stub/callback"}, nil)
+
+	rec := httptest.NewRecorder()
+	setOIDCStateCookie(rec, httptest.NewRequest("GET", "/auth/oidc/stub/login", nil), provider, "state-1")
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies", len(cookies))
+	}
+	c := cookies[0]
+	if !c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteLaxMode || c.Path != "/auth/oidc/stub/" {
+		t.Errorf("cookie attributes = %+v", c)
+	}
+	if c.Value == "state-1" {
+		t.Error("cookie holds the raw state")
+	}
+
+	cases := []struct {
+		name   string
+		cookie *http.Cookie
+		state  string
+		want   bool
+	}{
+		{"same browser", c, "state-1", true},
+		{"no cookie", nil, "state-1", false},
+		{"other flow's state", c, "state-2", false},
+		{"empty state", c, "", false},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest("GET", "/auth/oidc/stub/callback", nil)
+		if tc.cookie != nil {
+			r.AddCookie(&http.Cookie{Name: tc.cookie.Name, Value: tc.cookie.Value})
+		}
+		rec := httptest.NewRecorder()
+		if got := checkOIDCStateCookie(rec, r, provider, tc.state); got != tc.want {
+			t.Errorf("%s: check = %v, want %v", tc.name, got, tc.want)
+		}
+		if cleared := rec.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+			t.Errorf("%s: cookie not cleared: %+v", tc.name, cleared)
+		}
+	}
+}
+
+func TestFreshAuthentication(t *testing.T) {
+	started := time.Date(2025, 4, 20, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		authTime int64
+		want     bool
+	}{
+		{"after the flow started", started.Add(30 * time.Second).Unix(), true},
+		{"within clock skew", started.Add(-30 * time.Second).Unix(), true},
+		{"reused provider session", started.Add(-10 * time.Minute).Unix(), false},
+		{"no auth_time", 0, false},
+	}
+	for _, c := range cases {
+		if got := freshAuthentication(oidc.Claims{AuthTime: c.authTime}, started); got != c.want {
+			t.Errorf("%s: freshAuthentication = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"rwa/internal/mailer"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+	"rwa/internal/security"
+	"time"
+)
+
+type RequestChangePassword struct {
+	User struct {
+		CurrentPassword string `json:"currentPassword" validate:"required"`
+		NewPassword     string `json:"newPassword" validate:"required"`
+	} `json:"user"`
+}
+
+type RequestPasswordReset struct {
+	User struct {
+		Email string `json:"email" validate:"required,email"`
+	} `json:"user"`
+}
+
+type RequestConfirmPasswordReset struct {
+	User struct {
+		Token       string `json:"token" validate:"required"`
+		NewPassword string `json:"newPassword" validate:"required"`
+	} `json:"user"`
+}
+
+func (h *Handlers) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ChangePasswordHandler"
+
+	payload := RequestChangePassword{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(payload)
+	if err != nil {
+		h.log.Error(op+": validation failed", "error", err)
+		HandleFieldErrors(w, fieldErrors(err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	ctx := r.Context()
+	uid := ctx.Value("uid").(string)
+	currentToken := ctx.Value("token").(string)
+
+	user, err := h.UserRepository.GetUserForUpdate(uid)
+	if err != nil {
+		h.log.Error(op+": failed to get user", "error", err, "uid", uid)
+		HandleError(w, "Failed to retrieve user data", http.StatusUnauthorized)
+		return
+	}
+
+	if !security.ComparePasswords(payload.User.CurrentPassword, user.PasswordHash, user.PasswordSalt) {
+		h.log.Warn(op+": current password mismatch", "uid", uid)
+		HandleError(w, "Current password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	if problems := security.ValidatePassword(payload.User.NewPassword, user.Username, user.Email); len(problems) > 0 {
+		HandleFieldErrors(w, map[string][]string{"newPassword": problems}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	passwd, err := security.GeneratePasswd(payload.User.NewPassword)
+	if err != nil {
+		h.log.Error(op+": failed to hash password", "error", err, "uid", uid)
+		HandleError(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+	user.PasswordHash = passwd.Hash
+	user.PasswordSalt = passwd.Salt
+
+	if !h.audit(w, r, model.AuditPasswordChanged, model.AuditTargetUser, uid, nil) {
+		return
+	}
+	err = h.UserRepository.UpdateUser(user)
+	if err != nil {
+		h.log.Error(op+": failed to update password", "error", err, "uid", uid)
+		HandleError(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.revokeUserSessions(uid, currentToken)
+	if err != nil {
+		h.log.Error(op+": failed to revoke other sessions", "error", err, "uid", uid)
+		HandleError(w, "Failed to revoke other sessions", http.StatusInternalServerError)
+		return
+	}
+
+	response := model.UserResponse{
+		Id:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		Bio:           user.Bio,
+		Image:         user.Image,
+		EmailVerified: user.EmailVerified,
+		Private:       user.Private,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Token:         currentToken,
+	}
+	responseJSON := model.UserResponseJSON{User: response}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(responseJSON)
+	h.log.Info(op+": password changed", "uid", uid)
+	return
+}
+
+// RequestPasswordResetHandler answers 202 before looking the email up and
+// mails the reset token in the background, so neither the status nor the
+// response time tells whether the email is registered. Clients sending too
+// many requests get 429; an email sent too many resets gets no more mail for
+// a while.
+func (h *Handlers) RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.RequestPasswordResetHandler"
+
+	payload := RequestPasswordReset{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(payload)
+	if err != nil {
+		h.log.Error(op+": validation failed", "error", err)
+		HandleFieldErrors(w, fieldErrors(err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	ip := h.clientIP(r)
+	if ip != "" {
+		lockedUntil, err := h.LoginAttemptRepository.LockedUntil(resetIPKey(ip))
+		if err != nil {
+			h.log.Error(op+": failed to check throttle", "error", err)
+			HandleError(w, "Failed to start password reset", http.StatusInternalServerError)
+			return
+		}
+		if !lockedUntil.IsZero() {
+			h.log.Warn(op+": reset requests throttled", "ip", ip, "until", lockedUntil)
+			writeTooManyRequests(w, lockedUntil, "Too many password reset requests, try again later")
+			return
+		}
+		if _, err := h.LoginAttemptRepository.RecordFailure(resetIPKey(ip), h.ipThrottle()); err != nil {
+			h.log.Error(op+": failed to count reset request", "error", err)
+		}
+	}
+
+	event := h.auditEvent(r, "", model.AuditPasswordResetRequest, model.AuditTargetUser, "", nil)
+	go h.sendPasswordReset(payload.User.Email, event)
+	w.WriteHeader(http.StatusAccepted)
+	return
+}
+
+// sendPasswordReset mails a reset token to email if it belongs to a user who
+// has not been sent too many recently, and records event for it. It runs
+// after the request has been answered, so failures are only logged.
+func (h *Handlers) sendPasswordReset(email string, event model.AuditEvent) {
+	const op = "handlers.sendPasswordReset"
+
+	key := resetKey(email)
+	lockedUntil, err := h.LoginAttemptRepository.LockedUntil(key)
+	if err != nil {
+		h.log.Error(op+": failed to check throttle", "error", err)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		h.log.Warn(op+": resets for email throttled", "until", lockedUntil)
+		return
+	}
+	if _, err := h.LoginAttemptRepository.RecordFailure(key, h.accountThrottle()); err != nil {
+		h.log.Error(op+": failed to count reset request", "error", err)
+	}
+
+	user, err := h.UserRepository.GetUserForAuth(email, "")
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			h.log.Error(op+": failed to look up user", "error", err)
+		}
+		return
+	}
+
+	event.TargetID = user.ID
+	if err := h.AuditRepository.Record(event); err != nil {
+		h.log.Error(op+": failed to record audit event", "error", err, "uid", user.ID)
+		return
+	}
+
+	token, err := security.GenerateOpaqueToken()
+	if err != nil {
+		h.log.Error(op+": failed to generate reset token", "error", err, "uid", user.ID)
+		return
+	}
+
+	expiresAt := time.Now().Add(h.cfg.PasswordResetTTL)
+	err = h.UserRepository.CreatePasswordReset(user.ID, security.HashOpaqueToken(token), expiresAt)
+	if err != nil {
+		h.log.Error(op+": failed to store reset token", "error", err, "uid", user.ID)
+		return
+	}
+
+	err = h.Mailer.Send(context.Background(), mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Conduit password",
+		Body:    fmt.Sprintf("Use this token to set a new password: %s\nIt expires at %s.", token, expiresAt.Format(time.RFC1123)),
+	})
+	if err != nil {
+		h.log.Error(op+": failed to send reset mail", "error", err, "uid", user.ID)
+		return
+	}
+	h.log.Info(op+": password reset mailed", "uid", user.ID)
+}
+
+func (h *Handlers) ConfirmPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ConfirmPasswordResetHandler"
+
+	payload := RequestConfirmPasswordReset{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(payload)
+	if err != nil {
+		h.log.Error(op+": validation failed", "error", err)
+		HandleFieldErrors(w, fieldErrors(err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Check the policy before consuming the token so a rejected password
+	// does not burn it.
+	tokenHash := security.HashOpaqueToken(payload.User.Token)
+	uid, err := h.UserRepository.FindPasswordReset(tokenHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrResetTokenInvalid) {
+			HandleError(w, "Reset token is invalid or expired", http.StatusUnprocessableEntity)
+			return
+		}
+		h.log.Error(op+": failed to look up reset token", "error", err)
+		HandleError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.UserRepository.GetUserForUpdate(uid)
+	if err != nil {
+		h.log.Error(op+": failed to get user", "error", err, "uid", uid)
+		HandleError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	if problems := security.ValidatePassword(payload.User.NewPassword, user.Username, user.Email); len(problems) > 0 {
+		HandleFieldErrors(w, map[string][]string{"newPassword": problems}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	_, err = h.UserRepository.ConsumePasswordReset(tokenHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrResetTokenInvalid) {
+			HandleError(w, "Reset token is invalid or expired", http.StatusUnprocessableEntity)
+			return
+		}
+		h.log.Error(op+": failed to consume reset token", "error", err)
+		HandleError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	passwd, err := security.GeneratePasswd(payload.User.NewPassword)
+	if err != nil {
+		h.log.Error(op+": failed to hash password", "error", err, "uid", uid)
+		HandleError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	user.PasswordHash = passwd.Hash
+	user.PasswordSalt = passwd.Salt
+
+	if !h.auditAs(w, r, uid, model.AuditPasswordReset, model.AuditTargetUser, uid, nil) {
+		return
+	}
+	err = h.UserRepository.UpdateUser(user)
+	if err != nil {
+		h.log.Error(op+": failed to update password", "error", err, "uid", uid)
+		HandleError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.revokeUserSessions(uid, "")
+	if err != nil {
+		h.log.Error(op+": failed to revoke sessions", "error", err, "uid", uid)
+		HandleError(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	h.log.Info(op+": password reset completed", "uid", uid)
+	return
+}
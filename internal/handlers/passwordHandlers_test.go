@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Invalid requests are refused before anything is looked up or throttled.
+func TestRequestPasswordResetValidation(t *testing.T) {
+	h := &Handlers{V: newValidator(), log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	req := httptest.NewRequest(http.MethodPost, "/user/password/reset", strings.NewReader(`{"user":{"email":"not an email"}}`))
+	rec := httptest.NewRecorder()
+	h.RequestPasswordResetHandler(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	var body struct {
+		Errors map[string][]string `json:"errors"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Errors["email"]) != 1 {
+		t.Errorf("errors = %v, want one for email", body.Errors)
+	}
+}
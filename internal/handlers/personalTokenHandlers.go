@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+	"rwa/internal/security"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handlers) CreatePersonalTokenHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.CreatePersonalTokenHandler"
+
+	payload := model.CreatePersonalAccessTokenRequest{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(payload)
+	if err != nil {
+		h.log.Error(op+": validation failed", "error", err)
+		HandleFieldErrors(w, fieldErrors(err), http.StatusUnprocessableEntity)
+		return
+	}
+	if payload.Token.ExpiresAt != nil && !payload.Token.ExpiresAt.After(time.Now()) {
+		HandleFieldErrors(w, map[string][]string{"expiresAt": {"must be in the future"}}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	uid := r.Context().Value("uid").(string)
+	raw, err := security.GeneratePersonalToken()
+	if err != nil {
+		h.log.Error(op+": failed to generate token", "error", err, "uid", uid)
+		HandleError(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	scopes := slices.Compact(slices.Sorted(slices.Values(payload.Token.Scopes)))
+	token, err := h.UserRepository.CreatePersonalToken(uid, payload.Token.Name, security.HashOpaqueToken(raw), scopes, payload.Token.ExpiresAt,
+		h.auditEvent(r, uid, model.AuditTokenCreated, model.AuditTargetToken, "", map[string]any{"name": payload.Token.Name, "scopes": scopes}))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNameExists) {
+			HandleFieldErrors(w, map[string][]string{"name": {"has already been taken"}}, http.StatusUnprocessableEntity)
+			return
+		}
+		h.log.Error(op+": failed to store token", "error", err, "uid", uid)
+		HandleError(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+	token.Token = raw
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(model.PersonalAccessTokenResponse{Token: token})
+	h.log.Info(op+": personal token created", "uid", uid, "tokenID", token.ID)
+	return
+}
+
+func (h *Handlers) ListPersonalTokensHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ListPersonalTokensHandler"
+
+	uid := r.Context().Value("uid").(string)
+	tokens, err := h.UserRepository.ListPersonalTokens(uid)
+	if err != nil {
+		h.log.Error(op+": failed to list tokens", "error", err, "uid", uid)
+		HandleError(w, "Failed to list tokens", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.PersonalAccessTokensResponse{Tokens: tokens})
+	return
+}
+
+func (h *Handlers) DeletePersonalTokenHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.DeletePersonalTokenHandler"
+
+	uid := r.Context().Value("uid").(string)
+	id := mux.Vars(r)["id"]
+	err := h.UserRepository.DeletePersonalToken(uid, id, h.auditEvent(r, uid, model.AuditTokenDeleted, model.AuditTargetToken, id, nil))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenIsNotFound) {
+			HandleError(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		h.log.Error(op+": failed to revoke token", "error", err, "uid", uid, "tokenID", id)
+		HandleError(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.log.Info(op+": personal token revoked", "uid", uid, "tokenID", id)
+	return
+}
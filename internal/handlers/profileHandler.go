@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 	"rwa/internal/model"
 	"rwa/internal/repository"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -36,7 +38,21 @@ func (h *Handlers) FollowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.UserRepository.FollowUser(user, userToFollow.ID)
+	// Private accounts approve their followers; following them only files
+	// a request.
+	state := model.FollowActive
+	if userToFollow.Private {
+		state, err = h.UserRepository.RequestFollow(user, userToFollow.ID,
+			h.auditEvent(r, user, model.AuditFollowed, model.AuditTargetUser, userToFollow.ID, map[string]any{"pending": true}))
+	} else {
+		err = h.UserRepository.FollowUser(user, userToFollow.ID,
+			h.auditEvent(r, user, model.AuditFollowed, model.AuditTargetUser, userToFollow.ID, map[string]any{"pending": false}))
+	}
+	if errors.Is(err, repository.ErrBlocked) {
+		h.log.Warn("follow prevented by block", "op", op, "user", user, "userToFollowID", userToFollow.ID)
+		HandleError(w, "You cannot follow this user", http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		h.log.Error("failed to follow user", "op", op, "user", user, "userToFollowID", userToFollow.ID, "error", err)
 		http.Error(w, "Failed to follow user", http.StatusInternalServerError)
@@ -47,10 +63,10 @@ func (h *Handlers) FollowHandler(w http.ResponseWriter, r *http.Request) {
 		Username:  userToFollow.Username,
 		Bio:       userToFollow.Bio,
 		Image:     userToFollow.Image,
-		Following: true,
 		CreatedAt: userToFollow.CreatedAt,
 		UpdatedAt: userToFollow.UpdatedAt,
 	}
+	response.SetFollowState(state)
 	resp := model.ProfileResponse{
 		Profile: response,
 	}
@@ -58,7 +74,7 @@ func (h *Handlers) FollowHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(&resp)
 
-	h.log.Info("successfully followed user", "op", op, "user", user, "followedUser", userName)
+	h.log.Info("successfully followed user", "op", op, "user", user, "followedUser", userName, "pending", state == model.FollowPending)
 	return
 }
 
@@ -88,6 +104,9 @@ func (h *Handlers) UnFollowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.audit(w, r, model.AuditUnfollowed, model.AuditTargetUser, followedUser.ID, nil) {
+		return
+	}
 	err = h.UserRepository.UnFollowUser(followerUID, followedUser.ID)
 	if err != nil {
 		h.log.Error("failed to unfollow user", "op", op, "user", followerUID, "userToUnfollowID", followedUser.ID, "error", err)
@@ -99,7 +118,6 @@ func (h *Handlers) UnFollowHandler(w http.ResponseWriter, r *http.Request) {
 		Username:  followedUser.Username,
 		Bio:       followedUser.Bio,
 		Image:     followedUser.Image,
-		Following: false,
 		CreatedAt: followedUser.CreatedAt,
 		UpdatedAt: followedUser.UpdatedAt,
 	}
@@ -142,34 +160,45 @@ func (h *Handlers) CheckProfileHandler(w http.ResponseWriter, r *http.Request) {
 
 	followedUser, err := h.UserRepository.GetUserForAuth("", userName)
 	if err != nil {
-		h.log.Error("failed to get profile user", "op", op, "username", userName, "error", err)
 		if errors.Is(err, repository.ErrUserNotFound) {
+			// Renamed users keep their old profile URL for a while.
+			current, errResolve := h.UserRepository.ResolveFormerUsername(userName)
+			if errResolve == nil {
+				h.log.Info("redirecting former username", "op", op, "from", userName, "to", current)
+				http.Redirect(w, r, strings.TrimSuffix(r.URL.Path, userName)+url.PathEscape(current), http.StatusMovedPermanently)
+				return
+			}
+			h.log.Error("failed to get profile user", "op", op, "username", userName, "error", err)
 			http.Error(w, "Profile user not found", http.StatusNotFound)
 			return
 		}
+		h.log.Error("failed to get profile user", "op", op, "username", userName, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	isFollowing := false
+	var rel model.Relationship
 	if isAuthenticated {
-		isFollowingCheck, errCheck := h.UserRepository.CheckFollow(followerUID, followedUser.ID)
+		relCheck, errCheck := h.UserRepository.GetRelationship(followerUID, followedUser.ID)
 		if errCheck != nil {
 			h.log.Error("failed to check follow status", "op", op, "user", followerUID, "profileUser", userName, "error", errCheck)
 		} else {
-			isFollowing = isFollowingCheck
+			rel = relCheck
 		}
 	}
+	isFollowing := rel.FollowState()
 
 	response := model.Profile{
 		Id:        followedUser.ID,
 		Username:  followedUser.Username,
 		Bio:       followedUser.Bio,
 		Image:     followedUser.Image,
-		Following: isFollowing,
+		Blocking:  rel.Blocking,
+		Muting:    rel.Muting,
 		CreatedAt: followedUser.CreatedAt,
 		UpdatedAt: followedUser.UpdatedAt,
 	}
+	response.SetFollowState(isFollowing)
 	resp := model.ProfileResponse{
 		Profile: response,
 	}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// relationshipTarget resolves the {username} path variable for block and mute
+// endpoints, rejecting the caller themselves.
+func (h *Handlers) relationshipTarget(w http.ResponseWriter, r *http.Request, op string) (model.UserTableDB, bool) {
+	userName := mux.Vars(r)["username"]
+	target, err := h.UserRepository.GetUserForAuth("", userName)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			HandleError(w, "User not found", http.StatusNotFound)
+			return model.UserTableDB{}, false
+		}
+		h.log.Error(op+": failed to get user", "error", err, "username", userName)
+		HandleError(w, "Internal server error", http.StatusInternalServerError)
+		return model.UserTableDB{}, false
+	}
+	if target.ID == r.Context().Value("uid").(string) {
+		HandleError(w, "You cannot do this to yourself", http.StatusUnprocessableEntity)
+		return model.UserTableDB{}, false
+	}
+	return target, true
+}
+
+// writeRelationshipProfile responds with target's profile as seen by the
+// caller after a relationship change.
+func (h *Handlers) writeRelationshipProfile(w http.ResponseWriter, op string, viewerID string, target model.UserTableDB) {
+	rel, err := h.UserRepository.GetRelationship(viewerID, target.ID)
+	if err != nil {
+		h.log.Error(op+": failed to get relationship", "error", err, "uid", viewerID, "target", target.ID)
+		HandleError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	profile := model.Profile{
+		Id:        target.ID,
+		Username:  target.Username,
+		Bio:       target.Bio,
+		Image:     target.Image,
+		Blocking:  rel.Blocking,
+		Muting:    rel.Muting,
+		CreatedAt: target.CreatedAt,
+		UpdatedAt: target.UpdatedAt,
+	}
+	profile.SetFollowState(rel.FollowState())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.ProfileResponse{Profile: profile})
+}
+
+// BlockHandler blocks a user: follows in both directions are removed and
+// neither side can follow the other until the block is lifted.
+func (h *Handlers) BlockHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.BlockHandler"
+
+	target, ok := h.relationshipTarget(w, r, op)
+	if !ok {
+		return
+	}
+	uid := r.Context().Value("uid").(string)
+
+	if !h.audit(w, r, model.AuditBlocked, model.AuditTargetUser, target.ID, nil) {
+		return
+	}
+	if err := h.UserRepository.BlockUser(uid, target.ID); err != nil {
+		h.log.Error(op+": failed to block user", "error", err, "uid", uid, "target", target.ID)
+		HandleError(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+	h.writeRelationshipProfile(w, op, uid, target)
+	return
+}
+
+func (h *Handlers) UnblockHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.UnblockHandler"
+
+	target, ok := h.relationshipTarget(w, r, op)
+	if !ok {
+		return
+	}
+	uid := r.Context().Value("uid").(string)
+
+	if !h.audit(w, r, model.AuditUnblocked, model.AuditTargetUser, target.ID, nil) {
+		return
+	}
+	if err := h.UserRepository.UnblockUser(uid, target.ID); err != nil {
+		h.log.Error(op+": failed to unblock user", "error", err, "uid", uid, "target", target.ID)
+		HandleError(w, "Failed to unblock user", http.StatusInternalServerError)
+		return
+	}
+	h.writeRelationshipProfile(w, op, uid, target)
+	return
+}
+
+// MuteHandler hides a user's content from the caller's listings without
+// telling them or touching follows.
+func (h *Handlers) MuteHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.MuteHandler"
+
+	target, ok := h.relationshipTarget(w, r, op)
+	if !ok {
+		return
+	}
+	uid := r.Context().Value("uid").(string)
+
+	if err := h.UserRepository.MuteUser(uid, target.ID); err != nil {
+		h.log.Error(op+": failed to mute user", "error", err, "uid", uid, "target", target.ID)
+		HandleError(w, "Failed to mute user", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRelationshipProfile(w, op, uid, target)
+	return
+}
+
+func (h *Handlers) UnmuteHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.UnmuteHandler"
+
+	target, ok := h.relationshipTarget(w, r, op)
+	if !ok {
+		return
+	}
+	uid := r.Context().Value("uid").(string)
+
+	if err := h.UserRepository.UnmuteUser(uid, target.ID); err != nil {
+		h.log.Error(op+": failed to unmute user", "error", err, "uid", uid, "target", target.ID)
+		HandleError(w, "Failed to unmute user", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeRelationshipProfile(w, op, uid, target)
+	return
+}
+
+func (h *Handlers) ListBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ListBlocksHandler"
+
+	uid := r.Context().Value("uid").(string)
+	profiles, err := h.UserRepository.ListBlocked(uid)
+	if err != nil {
+		h.log.Error(op+": failed to list blocks", "error", err, "uid", uid)
+		HandleError(w, "Failed to list blocked users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.ProfilesResponse{Profiles: profiles})
+	return
+}
+
+func (h *Handlers) ListMutesHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ListMutesHandler"
+
+	uid := r.Context().Value("uid").(string)
+	profiles, err := h.UserRepository.ListMuted(uid)
+	if err != nil {
+		h.log.Error(op+": failed to list mutes", "error", err, "uid", uid)
+		HandleError(w, "Failed to list muted users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.ProfilesResponse{Profiles: profiles})
+	return
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"rwa/internal/model"
+	"rwa/internal/pkg"
+	"rwa/internal/repository"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// revision loads revision number of article, answering the request itself
+// when it can't.
+func (h *Handlers) revision(w http.ResponseWriter, op string, article model.DBArticle, number string) (model.Revision, bool) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 {
+		HandleError(w, "Revision not found", http.StatusNotFound)
+		return model.Revision{}, false
+	}
+	rev, err := h.ArticleRepository.GetRevision(article.Slug, n)
+	if err != nil {
+		if errors.Is(err, repository.ErrRevisionNotFound) {
+			HandleError(w, "Revision not found", http.StatusNotFound)
+			return model.Revision{}, false
+		}
+		h.log.Error(op+": failed to get revision", "error", err, "slug", article.Slug, "number", n)
+		HandleError(w, "Failed to get revision", http.StatusInternalServerError)
+		return model.Revision{}, false
+	}
+	return rev, true
+}
+
+func (h *Handlers) ListRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ListRevisionsHandler"
+
+	article, ok := h.ownArticle(w, r, op)
+	if !ok {
+		return
+	}
+	revisions, err := h.ArticleRepository.ListRevisions(article.Slug)
+	if err != nil {
+		h.log.Error(op+": failed to list revisions", "error", err, "slug", article.Slug)
+		HandleError(w, "Failed to list revisions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.RevisionsResponse{Revisions: revisions, RevisionsCount: len(revisions)})
+	return
+}
+
+func (h *Handlers) GetRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.GetRevisionHandler"
+
+	article, ok := h.ownArticle(w, r, op)
+	if !ok {
+		return
+	}
+	rev, ok := h.revision(w, op, article, mux.Vars(r)["number"])
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.RevisionResponse{Revision: rev})
+	return
+}
+
+// DiffRevisionsHandler compares the revisions given as from and to.
+func (h *Handlers) DiffRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.DiffRevisionsHandler"
+
+	article, ok := h.ownArticle(w, r, op)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	errs := map[string][]string{}
+	for _, name := range []string{"from", "to"} {
+		if n, err := strconv.Atoi(q.Get(name)); err != nil || n < 1 {
+			errs[name] = append(errs[name], "must be a revision number")
+		}
+	}
+	if len(errs) > 0 {
+		HandleFieldErrors(w, errs, http.StatusUnprocessableEntity)
+		return
+	}
+	from, ok := h.revision(w, op, article, q.Get("from"))
+	if !ok {
+		return
+	}
+	to, ok := h.revision(w, op, article, q.Get("to"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.RevisionDiffResponse{Diff: diffRevisions(from, to)})
+	return
+}
+
+func diffRevisions(from, to model.Revision) model.RevisionDiff {
+	diff := model.RevisionDiff{
+		From: from.Number,
+		To:   to.Number,
+		Body: pkg.DiffLines(from.Body, to.Body),
+	}
+	if diff.Body == nil {
+		diff.Body = []pkg.DiffLine{}
+	}
+	if from.Title != to.Title {
+		diff.Title = &model.FieldChange{From: from.Title, To: to.Title}
+	}
+	if from.Description != to.Description {
+		diff.Description = &model.FieldChange{From: from.Description, To: to.Description}
+	}
+	tags := model.TagsChange{Added: []string{}, Removed: []string{}}
+	for _, tag := range to.TagList {
+		if !slices.Contains(from.TagList, tag) {
+			tags.Added = append(tags.Added, tag)
+		}
+	}
+	for _, tag := range from.TagList {
+		if !slices.Contains(to.TagList, tag) {
+			tags.Removed = append(tags.Removed, tag)
+		}
+	}
+	if len(tags.Added) > 0 || len(tags.Removed) > 0 {
+		diff.TagList = &tags
+	}
+	return diff
+}
+
+// RestoreRevisionHandler saves an old revision's content as the newest
+// revision; the history in between is kept.
+func (h *Handlers) RestoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.RestoreRevisionHandler"
+
+	article, ok := h.ownArticle(w, r, op)
+	if !ok {
+		return
+	}
+	rev, ok := h.revision(w, op, article, mux.Vars(r)["number"])
+	if !ok {
+		return
+	}
+
+	edit := model.ArticleEdit{
+		EditorID:     r.Context().Value("uid").(string),
+		NewBase:      retitle(&article, rev.Title),
+		RestoredFrom: rev.Number,
+	}
+	article.Description = rev.Description
+	article.Body = rev.Body
+	article.TagList = rev.TagList
+
+	h.saveArticle(w, r, op, article, edit)
+	return
+}
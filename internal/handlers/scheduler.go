@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"context"
+	"time"
+)
+
+// RunPublishScheduler publishes due scheduled articles every interval until
+// ctx is cancelled.
+func (h *Handlers) RunPublishScheduler(ctx context.Context, interval time.Duration) {
+	const op = "handlers.RunPublishScheduler"
+
+	publish := func() {
+		slugs, err := h.ArticleRepository.PublishDue()
+		if err != nil {
+			h.log.Error("failed to publish scheduled articles", "op", op, "error", err)
+			return
+		}
+		for _, slug := range slugs {
+			h.log.Info("scheduled article published", "op", op, "slug", slug)
+		}
+	}
+
+	publish()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			publish()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"rwa/internal/repository"
+	"rwa/internal/security"
+)
+
+// revokeUserSessions invalidates every session token of uid except keep (pass
+// "" to revoke all of them). Tokens are added to the revocation list so that
+// stateless validation rejects them too, then removed from the tokens table.
+func (h *Handlers) revokeUserSessions(uid string, keep string) error {
+	const op = "handler.revokeUserSessions"
+
+	tokens, err := h.UserRepository.GetTokensByUID(uid)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tokens {
+		if t.Token == keep {
+			continue
+		}
+		claims, err := security.ParseToken(t.Token)
+		if err == nil {
+			if err := h.UserRepository.RevokeToken(claims.Jti, uid, claims.ExpiresAt); err != nil {
+				return err
+			}
+			h.Revocations.Revoke(claims.Jti, claims.ExpiresAt)
+		}
+		err = h.UserRepository.DeleteToken(t.Token)
+		if err != nil && !errors.Is(err, repository.ErrTokenIsNotFound) {
+			return err
+		}
+	}
+
+	h.log.Info("user sessions revoked", "op", op, "uid", uid, "count", len(tokens))
+	return nil
+}
+
+// sessionToken returns the user's current session token, creating one if the
+// user has none or the stored one no longer carries their role.
+func (h *Handlers) sessionToken(uid string, role string) (string, error) {
+	token, err := h.UserRepository.FindTokenByUID(uid)
+	if err != nil && !errors.Is(err, repository.ErrNoResult) {
+		return "", err
+	}
+	if err == nil {
+		if claims, err := security.ParseToken(token.Token); err == nil && claims.Role == role {
+			return token.Token, nil
+		}
+		// Expired or issued before the role changed: replace it so it is
+		// not found again on the next login.
+		err = h.UserRepository.DeleteToken(token.Token)
+		if err != nil && !errors.Is(err, repository.ErrTokenIsNotFound) {
+			return "", err
+		}
+	}
+	newToken := security.GenerateToken(uid, role)
+	if err := h.UserRepository.AddToken(newToken, uid); err != nil {
+		return "", err
+	}
+	return newToken, nil
+}
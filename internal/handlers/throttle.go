@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"rwa/internal/security"
+	"strings"
+	"time"
+)
+
+func (h *Handlers) accountThrottle() security.ThrottlePolicy {
+	return security.ThrottlePolicy{
+		FreeAttempts: h.cfg.LoginFreeAttempts,
+		BaseDelay:    h.cfg.LoginBackoffBase,
+		MaxDelay:     h.cfg.LoginBackoffMax,
+		LockoutAfter: h.cfg.LoginLockoutAfter,
+		LockoutFor:   h.cfg.LoginLockoutDuration,
+		Window:       h.cfg.LoginAttemptWindow,
+	}
+}
+
+func (h *Handlers) ipThrottle() security.ThrottlePolicy {
+	p := h.accountThrottle()
+	p.FreeAttempts *= 10
+	p.LockoutAfter = h.cfg.LoginIPLockoutAfter
+	return p
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginKeys are the throttle keys a login attempt is checked against. The IP
+// key is left out when the address is unknown, so that such callers do not
+// all share one bucket.
+func loginKeys(email string, ip string) []string {
+	keys := []string{accountKey(email)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
+
+// resetKey and resetIPKey count password reset requests apart from logins.
+func resetKey(email string) string {
+	return "reset:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func resetIPKey(ip string) string {
+	return "reset-ip:" + ip
+}
+
+// clientIP returns the caller's address. A RemoteAddr that does not parse
+// (a unix socket, say) is returned as is; "" only comes back when there is
+// no RemoteAddr at all, and callers then skip per-IP throttling.
+// X-Forwarded-For is honoured only when the deployment says a trusted proxy
+// sets it, and then read from the right: the leftmost entries are whatever
+// the client sent, so the first entry that is not a trusted proxy is the
+// address the outermost trusted proxy saw.
+func (h *Handlers) clientIP(r *http.Request) string {
+	addr, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !h.cfg.TrustProxyHeaders || (len(h.cfg.TrustedProxies) > 0 && !h.trustedProxy(addr)) {
+		return addr.String()
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			// Whoever wrote a malformed entry is not trusted; the last
+			// good hop is the best we know.
+			break
+		}
+		addr = hop
+		if !h.trustedProxy(addr) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+func (h *Handlers) trustedProxy(addr netip.Addr) bool {
+	for _, p := range h.cfg.TrustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr parses an IP address with or without a port.
+func parseAddr(s string) (netip.Addr, bool) {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap().WithZone(""), true
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func writeTooManyRequests(w http.ResponseWriter, until time.Time, msg string) {
+	seconds := int(math.Ceil(time.Until(until).Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", seconds))
+	HandleError(w, msg, http.StatusTooManyRequests)
+}
+
+// recordLoginFailure counts a failed login against both the account and the
+// client IP.
+func (h *Handlers) recordLoginFailure(email string, ip string) {
+	const op = "handler.recordLoginFailure"
+	if _, err := h.LoginAttemptRepository.RecordFailure(accountKey(email), h.accountThrottle()); err != nil {
+		h.log.Error("failed to record account failure", "op", op, "error", err)
+	}
+	if ip == "" {
+		return
+	}
+	if _, err := h.LoginAttemptRepository.RecordFailure(ipKey(ip), h.ipThrottle()); err != nil {
+		h.log.Error("failed to record ip failure", "op", op, "error", err)
+	}
+}
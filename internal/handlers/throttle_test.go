@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"rwa/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	cases := []struct {
+		name    string
+		trust   bool
+		proxies []netip.Prefix
+		remote  string
+		xff     []string
+		want    string
+	}{
+		{"headers ignored by default", false, nil, "203.0.113.7:4000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"single proxy takes rightmost", true, nil, "10.0.0.2:4000", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"spoofed leftmost entry skipped", true, proxies, "10.0.0.2:4000", []string{"1.1.1.1, 198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		{"repeated headers are joined", true, proxies, "10.0.0.2:4000", []string{"1.1.1.1", "198.51.100.1"}, "198.51.100.1"},
+		{"untrusted peer ignores header", true, proxies, "203.0.113.7:4000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"malformed entry stops the walk", true, proxies, "10.0.0.2:4000", []string{"198.51.100.1, <script>, 10.0.0.3"}, "10.0.0.3"},
+		{"only proxies", true, proxies, "10.0.0.2:4000", []string{"10.0.0.4, 10.0.0.3"}, "10.0.0.4"},
+		{"entry with port", true, nil, "10.0.0.2:4000", []string{"198.51.100.1:555"}, "198.51.100.1"},
+		{"ipv6 entry", true, nil, "10.0.0.2:4000", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"mapped ipv4 peer", false, nil, "[::ffff:203.0.113.7]:4000", nil, "203.0.113.7"},
+		{"no header", true, nil, "10.0.0.2:4000", nil, "10.0.0.2"},
+		{"unparsable peer", false, nil, "pipe", nil, "pipe"},
+		{"no peer", true, nil, "", []string{"198.51.100.1"}, ""},
+		{"zoned peer", false, nil, "[fe80::1%eth0]:4000", nil, "fe80::1"},
+		{"oversized entry", true, nil, "10.0.0.2:4000", []string{"1.1.1.1, " + strings.Repeat("9", 100)}, "10.0.0.2"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := &Handlers{cfg: config.Config{TrustProxyHeaders: c.trust, TrustedProxies: c.proxies}}
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = c.remote
+			for _, v := range c.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := h.clientIP(r); got != c.want {
+				t.Errorf("clientIP = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
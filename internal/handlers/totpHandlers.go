@@ -0,0 +1,383 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"rwa/internal/model"
+	"rwa/internal/repository"
+	"rwa/internal/security"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const recoveryCodeCount = 10
+
+type RequestTOTPCode struct {
+	User struct {
+		Code string `json:"code" validate:"required,len=6,numeric"`
+	} `json:"user"`
+}
+
+type RequestCompleteLogin struct {
+	User struct {
+		Challenge    string `json:"challenge" validate:"required"`
+		Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+		RecoveryCode string `json:"recoveryCode" validate:"required_without=Code"`
+	} `json:"user"`
+}
+
+// startLoginChallenge answers a correct password for a 2FA-enabled account
+// with a short-lived challenge instead of a session token.
+func (h *Handlers) startLoginChallenge(w http.ResponseWriter, uid string) {
+	const op = "handlers.startLoginChallenge"
+
+	challenge, err := security.GenerateOpaqueToken()
+	if err != nil {
+		h.log.Error(op+": failed to generate challenge", "error", err, "uid", uid)
+		HandleError(w, "Failed to start two-factor login", http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := time.Now().Add(h.cfg.LoginChallengeTTL)
+	err = h.UserRepository.CreateLoginChallenge(uid, security.HashOpaqueToken(challenge), expiresAt)
+	if err != nil {
+		h.log.Error(op+": failed to store challenge", "error", err, "uid", uid)
+		HandleError(w, "Failed to start two-factor login", http.StatusInternalServerError)
+		return
+	}
+
+	resp := model.LoginChallengeResponse{Challenge: model.LoginChallenge{
+		Token:     challenge,
+		Methods:   []string{"totp", "recovery"},
+		ExpiresAt: expiresAt,
+	}}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+	h.log.Info(op+": two-factor challenge issued", "uid", uid)
+}
+
+// verifyTOTP checks a TOTP code, rejecting replays.
+func (h *Handlers) verifyTOTP(user model.UserTableDB, code string) (bool, error) {
+	secret, err := security.OpenSecret(user.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := security.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	err = h.UserRepository.MarkTOTPStep(user.ID, step)
+	if errors.Is(err, repository.ErrTOTPStepReused) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// completeLoginChallenge checks the second factor and closes the challenge.
+// A recovery code is only burned together with closing the challenge.
+func (h *Handlers) completeLoginChallenge(user model.UserTableDB, challengeHash string, code string, recoveryCode string) (bool, error) {
+	if code == "" {
+		hash := security.HashOpaqueToken(security.NormalizeRecoveryCode(recoveryCode))
+		return h.UserRepository.ConsumeLoginChallengeWithRecoveryCode(challengeHash, user.ID, hash)
+	}
+	ok, err := h.verifyTOTP(user, code)
+	if !ok || err != nil {
+		return ok, err
+	}
+	return true, h.UserRepository.ConsumeLoginChallenge(challengeHash)
+}
+
+// CompleteLoginHandler finishes a two-factor login. Every attempt counts
+// against the challenge before the code is checked, and wrong codes count
+// against the account and client IP like wrong passwords, so neither
+// parallel guesses nor fresh challenges get around the limits.
+func (h *Handlers) CompleteLoginHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.CompleteLoginHandler"
+
+	payload := RequestCompleteLogin{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(payload)
+	if err != nil {
+		h.log.Error(op+": validation failed", "error", err)
+		HandleError(w, err.(validator.ValidationErrors).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	challengeHash := security.HashOpaqueToken(payload.User.Challenge)
+	uid, err := h.UserRepository.ClaimLoginChallenge(challengeHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrChallengeInvalid) {
+			HandleError(w, "Login challenge is invalid or expired", http.StatusUnauthorized)
+			return
+		}
+		h.log.Error(op+": failed to claim challenge", "error", err)
+		HandleError(w, "Failed to complete login", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.UserRepository.GetUserForUpdate(uid)
+	if err != nil {
+		h.log.Error(op+": failed to get user", "error", err, "uid", uid)
+		HandleError(w, "Failed to complete login", http.StatusInternalServerError)
+		return
+	}
+
+	if user.SuspendedAt != nil {
+		h.log.Warn(op+": suspended user rejected", "uid", uid)
+		HandleError(w, "Account is suspended", http.StatusForbidden)
+		return
+	}
+
+	ip := h.clientIP(r)
+	lockedUntil, err := h.LoginAttemptRepository.LockedUntil(loginKeys(user.Email, ip)...)
+	if err != nil {
+		h.log.Error(op+": failed to check login throttling", "error", err, "uid", uid)
+		HandleError(w, "Failed to complete login", http.StatusInternalServerError)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		h.log.Warn(op+": login throttled", "uid", uid, "ip", ip, "until", lockedUntil)
+		writeTooManyRequests(w, lockedUntil, "Too many failed login attempts, try again later")
+		return
+	}
+
+	ok, err := h.completeLoginChallenge(user, challengeHash, payload.User.Code, payload.User.RecoveryCode)
+	if errors.Is(err, repository.ErrChallengeInvalid) {
+		h.log.Warn(op+": challenge already used", "uid", uid)
+		HandleError(w, "Login challenge is invalid or expired", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		h.log.Error(op+": failed to verify second factor", "error", err, "uid", uid)
+		HandleError(w, "Failed to complete login", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		h.recordLoginFailure(user.Email, ip)
+		h.auditFailure(r, "", model.AuditLoginFailed, model.AuditTargetUser, uid, map[string]any{"reason": "invalid_second_factor"})
+		h.log.Warn(op+": invalid second factor", "uid", uid)
+		HandleError(w, "Invalid two-factor code", http.StatusUnauthorized)
+		return
+	}
+
+	if !h.auditAs(w, r, uid, model.AuditLoginSucceeded, model.AuditTargetUser, uid, map[string]any{"method": "password+totp"}) {
+		return
+	}
+
+	if err := h.LoginAttemptRepository.Reset(accountKey(user.Email)); err != nil {
+		h.log.Error(op+": failed to reset login attempts", "error", err, "uid", uid)
+	}
+
+	token, err := h.sessionToken(uid, user.Role)
+	if err != nil {
+		h.log.Error(op+": failed to get session token", "error", err, "uid", uid)
+		HandleError(w, "Failed to retrieve authentication token", http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := model.UserResponse{
+		Id:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		Bio:           user.Bio,
+		Image:         user.Image,
+		EmailVerified: user.EmailVerified,
+		Private:       user.Private,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Token:         token,
+	}
+	responseJSON := model.UserResponseJSON{User: response}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(responseJSON)
+	h.log.Info(op+": two-factor login completed", "uid", uid)
+	return
+}
+
+func (h *Handlers) EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.EnrollTOTPHandler"
+
+	uid := r.Context().Value("uid").(string)
+	user, err := h.UserRepository.GetUserForUpdate(uid)
+	if err != nil {
+		h.log.Error(op+": failed to get user", "error", err, "uid", uid)
+		HandleError(w, "Failed to retrieve user data", http.StatusUnprocessableEntity)
+		return
+	}
+	if user.TOTPEnabled {
+		HandleError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		h.log.Error(op+": failed to generate secret", "error", err, "uid", uid)
+		HandleError(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+	sealed, err := security.SealSecret(secret)
+	if err != nil {
+		h.log.Error(op+": failed to seal secret", "error", err, "uid", uid)
+		HandleError(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.UserRepository.SetPendingTOTPSecret(uid, sealed)
+	if err != nil {
+		h.log.Error(op+": failed to store secret", "error", err, "uid", uid)
+		HandleError(w, "Failed to start enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	resp := model.TOTPEnrollmentResponse{TOTP: model.TOTPEnrollment{
+		Secret:     secret,
+		OtpauthURI: security.TOTPURI(h.cfg.TOTPIssuer, user.Email, secret),
+	}}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+	h.log.Info(op+": totp enrollment started", "uid", uid)
+	return
+}
+
+func (h *Handlers) ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ConfirmTOTPHandler"
+
+	payload := RequestTOTPCode{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(payload)
+	if err != nil {
+		h.log.Error(op+": validation failed", "error", err)
+		HandleError(w, err.(validator.ValidationErrors).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	uid := r.Context().Value("uid").(string)
+	user, err := h.UserRepository.GetUserForUpdate(uid)
+	if err != nil {
+		h.log.Error(op+": failed to get user", "error", err, "uid", uid)
+		HandleError(w, "Failed to retrieve user data", http.StatusUnprocessableEntity)
+		return
+	}
+	if user.TOTPEnabled {
+		HandleError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if user.TOTPSecret == "" {
+		HandleError(w, "Two-factor enrollment has not been started", http.StatusConflict)
+		return
+	}
+
+	secret, err := security.OpenSecret(user.TOTPSecret)
+	if err != nil {
+		h.log.Error(op+": failed to open secret", "error", err, "uid", uid)
+		HandleError(w, "Failed to confirm enrollment", http.StatusInternalServerError)
+		return
+	}
+	step, ok := security.ValidateTOTP(secret, payload.User.Code, time.Now())
+	if !ok {
+		HandleError(w, "Invalid two-factor code", http.StatusUnprocessableEntity)
+		return
+	}
+
+	codes, err := security.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		h.log.Error(op+": failed to generate recovery codes", "error", err, "uid", uid)
+		HandleError(w, "Failed to confirm enrollment", http.StatusInternalServerError)
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = security.HashOpaqueToken(c)
+	}
+
+	if !h.audit(w, r, model.AuditTOTPEnabled, model.AuditTargetUser, uid, nil) {
+		return
+	}
+
+	err = h.UserRepository.EnableTOTP(uid, step, hashes)
+	if err != nil {
+		h.log.Error(op+": failed to enable totp", "error", err, "uid", uid)
+		HandleError(w, "Failed to confirm enrollment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.RecoveryCodesResponse{RecoveryCodes: codes})
+	h.log.Info(op+": totp enabled", "uid", uid)
+	return
+}
+
+func (h *Handlers) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.DisableTOTPHandler"
+
+	payload := RequestTOTPCode{}
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		h.log.Error(op+": failed to decode request body", "error", err)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(payload)
+	if err != nil {
+		h.log.Error(op+": validation failed", "error", err)
+		HandleError(w, err.(validator.ValidationErrors).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	uid := r.Context().Value("uid").(string)
+	user, err := h.UserRepository.GetUserForUpdate(uid)
+	if err != nil {
+		h.log.Error(op+": failed to get user", "error", err, "uid", uid)
+		HandleError(w, "Failed to retrieve user data", http.StatusUnprocessableEntity)
+		return
+	}
+	if !user.TOTPEnabled {
+		HandleError(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+
+	ok, err := h.verifyTOTP(user, payload.User.Code)
+	if err != nil {
+		h.log.Error(op+": failed to verify code", "error", err, "uid", uid)
+		HandleError(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		HandleError(w, "Invalid two-factor code", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !h.audit(w, r, model.AuditTOTPDisabled, model.AuditTargetUser, uid, nil) {
+		return
+	}
+
+	err = h.UserRepository.DisableTOTP(uid)
+	if err != nil {
+		h.log.Error(op+": failed to disable totp", "error", err, "uid", uid)
+		HandleError(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	h.log.Info(op+": totp disabled", "uid", uid)
+	return
+}
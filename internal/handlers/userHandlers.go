@@ -5,16 +5,18 @@ import (
 	"errors"
 	"net/http"
 	"rwa/internal/model"
+	"rwa/internal/repository"
 	"rwa/internal/security"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
 type RequestUser struct {
 	User struct {
-		Username string `json:"username" validate:"required,min=5"`
+		Username string `json:"username" validate:"required,username"`
 		Email    string `json:"email" validate:"required,email"`
-		Password string `json:"password" validate:"required,min=5"`
+		Password string `json:"password" validate:"required"`
 	} `json:"user"`
 }
 
@@ -27,19 +29,33 @@ func (h *Handlers) UserRegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		h.log.Error(op+": failed to decode request body", "error", err)
-		HandleError(w, "Invalid request format", http.StatusUnprocessableEntity)
+		handleDecodeError(w, err, "Invalid request format")
 		return
 	}
 
+	errs := make(map[string][]string)
 	err = h.V.Struct(user)
 	if err != nil {
-		h.log.Error(op+": validation failed", "error", err)
-		HandleError(w, err.(validator.ValidationErrors).Error(), http.StatusUnprocessableEntity)
+		errs = fieldErrors(err)
+	}
+	if user.User.Password != "" {
+		if problems := security.ValidatePassword(user.User.Password, user.User.Username, user.User.Email); len(problems) > 0 {
+			errs["password"] = append(errs["password"], problems...)
+		}
+	}
+	if len(errs) > 0 {
+		h.log.Error(op+": validation failed", "errors", errs)
+		HandleFieldErrors(w, errs, http.StatusUnprocessableEntity)
 		return
 	}
 
 	h.log.Info(op+": processing registration", "username", user.User.Username)
-	err = h.UserRepository.RegisterUser(user.User.Username, user.User.Email, user.User.Password)
+	err = h.UserRepository.RegisterUser(user.User.Username, user.User.Email, user.User.Password,
+		h.auditEvent(r, "", model.AuditUserRegistered, model.AuditTargetUser, "", nil))
+	if errors.Is(err, repository.ErrUsernameAlreadyExists) {
+		HandleFieldErrors(w, map[string][]string{"username": {"has already been taken"}}, http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		h.log.Error(op+": failed to register user", "error", err)
 		HandleError(w, "Failed to register user", http.StatusUnprocessableEntity)
@@ -53,7 +69,7 @@ func (h *Handlers) UserRegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token := security.GenerateToken(NewUser.ID)
+	token := security.GenerateToken(NewUser.ID, NewUser.Role)
 	err = h.UserRepository.AddToken(token, NewUser.ID)
 	if err != nil {
 		h.log.Error(op+": failed to add token to database", "error", err)
@@ -61,15 +77,24 @@ func (h *Handlers) UserRegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The account is usable right away; a failed mail can be retried via
+	// the resend endpoint.
+	err = h.sendEmailVerification(r.Context(), NewUser.ID, NewUser.Email)
+	if err != nil {
+		h.log.Error(op+": failed to send email verification", "error", err, "uid", NewUser.ID)
+	}
+
 	response := model.UserResponse{
-		Id:        NewUser.ID,
-		Email:     NewUser.Email,
-		Username:  NewUser.Username,
-		Bio:       NewUser.Bio,
-		Image:     NewUser.Image,
-		CreatedAt: NewUser.CreatedAt,
-		UpdatedAt: NewUser.UpdatedAt,
-		Token:     token,
+		Id:            NewUser.ID,
+		Email:         NewUser.Email,
+		Username:      NewUser.Username,
+		Bio:           NewUser.Bio,
+		Image:         NewUser.Image,
+		EmailVerified: NewUser.EmailVerified,
+		Private:       NewUser.Private,
+		CreatedAt:     NewUser.CreatedAt,
+		UpdatedAt:     NewUser.UpdatedAt,
+		Token:         token,
 	}
 	responseJSON := model.UserResponseJSON{User: response}
 
@@ -92,22 +117,21 @@ func (h *Handlers) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.UserRepository.FindTokenByUID(uid)
-	if err != nil {
-		h.log.Error(op+": failed to get token", "error", err, "uid", uid)
-		HandleError(w, "Failed to retrieve authentication token", http.StatusUnprocessableEntity)
-		return
-	}
+	// Echo the credential the caller authenticated with; for personal
+	// access tokens this must not reveal the user's session token.
+	token := ctx.Value("token").(string)
 
 	response := model.UserResponse{
-		Id:        user.ID,
-		Email:     user.Email,
-		Username:  user.Username,
-		Bio:       user.Bio,
-		Image:     user.Image,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		Token:     token.Token,
+		Id:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		Bio:           user.Bio,
+		Image:         user.Image,
+		EmailVerified: user.EmailVerified,
+		Private:       user.Private,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Token:         token,
 	}
 	responseJSON := model.UserResponseJSON{User: response}
 	w.Header().Set("Content-Type", "application/json")
@@ -129,7 +153,7 @@ func (h *Handlers) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&loginPayload)
 	if err != nil {
 		h.log.Error(op+": failed to decode request body", "error", err)
-		HandleError(w, "Invalid request format", http.StatusUnprocessableEntity)
+		handleDecodeError(w, err, "Invalid request format")
 		return
 	}
 
@@ -140,43 +164,88 @@ func (h *Handlers) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check throttling before touching the password hash: every comparison
+	// costs a full argon2 run.
+	ip := h.clientIP(r)
+	lockedUntil, err := h.LoginAttemptRepository.LockedUntil(loginKeys(loginPayload.User.Email, ip)...)
+	if err != nil {
+		h.log.Error(op+": failed to check login throttling", "error", err)
+		HandleError(w, "Failed to process login", http.StatusInternalServerError)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		h.log.Warn(op+": login throttled", "email", loginPayload.User.Email, "ip", ip, "until", lockedUntil)
+		writeTooManyRequests(w, lockedUntil, "Too many failed login attempts, try again later")
+		return
+	}
+
 	user, err := h.UserRepository.GetUserForAuth(loginPayload.User.Email, "")
 	if err != nil {
 		h.log.Error(op+": user not found", "error", err, "email", loginPayload.User.Email)
+		// Answer no faster than a wrong password would, so timing does not
+		// tell which emails have accounts.
+		security.DummyCheckPassword(loginPayload.User.Password)
+		h.recordLoginFailure(loginPayload.User.Email, ip)
+		h.auditFailure(r, "", model.AuditLoginFailed, model.AuditTargetUser, "", map[string]any{"reason": "unknown_email", "emailHash": security.PseudonymizeEmail(loginPayload.User.Email)})
 		HandleError(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	if !security.ComparePasswords(loginPayload.User.Password, user.PasswordHash, user.PasswordSalt) {
+	ok, needsRehash := security.CheckPassword(loginPayload.User.Password, user.PasswordHash, user.PasswordSalt)
+	if !ok {
 		h.log.Error(op+": invalid password", "uid", user.ID)
+		h.recordLoginFailure(loginPayload.User.Email, ip)
+		h.auditFailure(r, "", model.AuditLoginFailed, model.AuditTargetUser, user.ID, map[string]any{"reason": "invalid_password"})
 		HandleError(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	if needsRehash {
+		h.rehashPassword(user.ID, loginPayload.User.Password)
+	}
 
-	token, err := h.UserRepository.FindTokenByUID(user.ID)
-	if errors.Is(err, errors.New("no result")) {
-		token.Token = security.GenerateToken(user.ID)
-		err = h.UserRepository.AddToken(token.Token, user.ID)
-		if err != nil {
-			h.log.Error(op+": failed to add token", "error", err, "uid", user.ID)
-			HandleError(w, "Failed to create authentication token", http.StatusUnprocessableEntity)
+	if user.SuspendedAt != nil {
+		h.log.Warn(op+": suspended user rejected", "uid", user.ID)
+		h.auditFailure(r, "", model.AuditLoginFailed, model.AuditTargetUser, user.ID, map[string]any{"reason": "suspended"})
+		HandleError(w, "Account is suspended", http.StatusForbidden)
+		return
+	}
+
+	if user.TOTPEnabled {
+		if !h.auditAs(w, r, user.ID, model.AuditLoginChallenged, model.AuditTargetUser, user.ID, map[string]any{"method": "password"}) {
 			return
 		}
-	} else if err != nil {
-		h.log.Error(op+": failed to find token", "error", err, "uid", user.ID)
+		h.startLoginChallenge(w, user.ID)
+		return
+	}
+
+	if !h.auditAs(w, r, user.ID, model.AuditLoginSucceeded, model.AuditTargetUser, user.ID, map[string]any{"method": "password"}) {
+		return
+	}
+
+	// With two-factor login the failures are cleared once the second factor
+	// is accepted, so a known password does not reset the code budget.
+	if err := h.LoginAttemptRepository.Reset(accountKey(loginPayload.User.Email)); err != nil {
+		h.log.Error(op+": failed to reset login attempts", "error", err, "uid", user.ID)
+	}
+
+	token, err := h.sessionToken(user.ID, user.Role)
+	if err != nil {
+		h.log.Error(op+": failed to get session token", "error", err, "uid", user.ID)
 		HandleError(w, "Failed to retrieve authentication token", http.StatusUnprocessableEntity)
 		return
 	}
 
 	response := model.UserResponse{
-		Id:        user.ID,
-		Email:     user.Email,
-		Username:  user.Username,
-		Bio:       user.Bio,
-		Image:     user.Image,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		Token:     token.Token,
+		Id:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		Bio:           user.Bio,
+		Image:         user.Image,
+		EmailVerified: user.EmailVerified,
+		Private:       user.Private,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Token:         token,
 	}
 	responseJSON := model.UserResponseJSON{User: response}
 	w.Header().Set("Content-Type", "application/json")
@@ -190,17 +259,28 @@ func (h *Handlers) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	updatePayload := struct {
 		User struct {
-			Email    string `json:"email" `
-			Username string `json:"username"`
+			Email    string `json:"email" validate:"omitempty,email"`
+			Username string `json:"username" validate:"omitempty,username"`
 			Bio      string `json:"bio"`
 			Image    string `json:"image"`
+			Private  *bool  `json:"private"`
+			// CurrentPassword or ReauthToken confirms a change of email or
+			// username.
+			CurrentPassword string `json:"currentPassword"`
+			ReauthToken     string `json:"reauthToken"`
 		}
 	}{}
 
 	err := json.NewDecoder(r.Body).Decode(&updatePayload)
 	if err != nil {
 		h.log.Error(op+": failed to decode request body", "error", err)
-		HandleError(w, "Invalid request format", http.StatusUnprocessableEntity)
+		handleDecodeError(w, err, "Invalid request format")
+		return
+	}
+
+	err = h.V.Struct(updatePayload)
+	if err != nil {
+		HandleFieldErrors(w, fieldErrors(err), http.StatusUnprocessableEntity)
 		return
 	}
 
@@ -213,51 +293,104 @@ func (h *Handlers) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Email and username are credentials: with a new email a password reset
+	// takes the account over. Personal access tokens may only edit the
+	// public profile, and a session must confirm the current password.
+	emailChange := updatePayload.User.Email != "" && updatePayload.User.Email != user.Email
+	usernameChange := updatePayload.User.Username != "" && updatePayload.User.Username != user.Username
+	if isPersonalToken(r) && (emailChange || usernameChange || updatePayload.User.Private != nil) {
+		h.log.Warn(op+": personal access token tried to change account settings", "uid", uid)
+		HandleError(w, "Changing email, username or privacy requires a session token", http.StatusForbidden)
+		return
+	}
+	if emailChange || usernameChange {
+		if updatePayload.User.CurrentPassword == "" && updatePayload.User.ReauthToken == "" {
+			HandleFieldErrors(w, map[string][]string{"currentPassword": {"can't be blank"}}, http.StatusUnprocessableEntity)
+			return
+		}
+		if !h.confirmIdentity(w, r, user, updatePayload.User.CurrentPassword, updatePayload.User.ReauthToken, op) {
+			return
+		}
+	}
+
 	// Update user fields if provided
-	if updatePayload.User.Email != "" {
+	var changed []string
+	emailChanged := false
+	if emailChange {
 		user.Email = updatePayload.User.Email
+		user.EmailVerified = false
+		emailChanged = true
+		changed = append(changed, "email")
 	}
-	if updatePayload.User.Username != "" {
+	formerUsername := user.Username
+	if usernameChange {
 		user.Username = updatePayload.User.Username
 	}
 	if updatePayload.User.Bio != "" {
 		user.Bio = updatePayload.User.Bio
+		changed = append(changed, "bio")
 	}
 	if updatePayload.User.Image != "" {
 		user.Image = updatePayload.User.Image
+		changed = append(changed, "image")
+	}
+	wentPublic := false
+	if updatePayload.User.Private != nil {
+		wentPublic = user.Private && !*updatePayload.User.Private
+		user.Private = *updatePayload.User.Private
+		changed = append(changed, "private")
 	}
 
-	err = h.UserRepository.UpdateUser(user)
+	// The rename and the other fields are saved together, so a rejected
+	// email cannot leave the old username released.
+	var events []model.AuditEvent
+	if usernameChange {
+		events = append(events, h.auditEvent(r, uid, model.AuditUserRenamed, model.AuditTargetUser, uid, map[string]any{"from": formerUsername, "to": user.Username}))
+	}
+	if len(changed) > 0 {
+		events = append(events, h.auditEvent(r, uid, model.AuditUserUpdated, model.AuditTargetUser, uid, map[string]any{"fields": changed}))
+	}
+	err = h.UserRepository.UpdateProfile(user, time.Now().Add(h.cfg.UsernameReservation), events...)
 	if err != nil {
-		h.log.Error(op+": failed to update user", "error", err, "uid", uid)
-		HandleError(w, "Failed to update user data", http.StatusBadRequest)
+		switch {
+		case errors.Is(err, repository.ErrUsernameAlreadyExists):
+			HandleFieldErrors(w, map[string][]string{"username": {"has already been taken"}}, http.StatusUnprocessableEntity)
+		case errors.Is(err, repository.ErrEmailAlreadyExists):
+			HandleFieldErrors(w, map[string][]string{"email": {"has already been taken"}}, http.StatusUnprocessableEntity)
+		default:
+			h.log.Error(op+": failed to update user", "error", err, "uid", uid)
+			HandleError(w, "Failed to update user data", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	token, err := h.UserRepository.FindTokenByUID(user.ID)
-	if errors.Is(err, errors.New("no result")) {
-		token.Token = security.GenerateToken(user.ID)
-		err = h.UserRepository.AddToken(token.Token, user.ID)
+	if wentPublic {
+		err = h.UserRepository.ApproveAllFollowRequests(uid)
 		if err != nil {
-			h.log.Error(op+": failed to add token after update", "error", err, "uid", user.ID)
-			HandleError(w, "Failed to create authentication token", http.StatusUnprocessableEntity)
-			return
+			h.log.Error(op+": failed to approve pending follow requests", "error", err, "uid", uid)
 		}
-	} else if err != nil {
-		h.log.Error(op+": failed to find token after update", "error", err, "uid", user.ID)
-		HandleError(w, "Failed to retrieve authentication token", http.StatusUnprocessableEntity)
-		return
 	}
 
+	if emailChanged {
+		err = h.sendEmailVerification(r.Context(), user.ID, user.Email)
+		if err != nil {
+			h.log.Error(op+": failed to send email verification", "error", err, "uid", uid)
+		}
+	}
+
+	token := ctx.Value("token").(string)
+
 	response := model.UserResponse{
-		Id:        user.ID,
-		Email:     user.Email,
-		Username:  user.Username,
-		Bio:       user.Bio,
-		Image:     user.Image,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		Token:     token.Token,
+		Id:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		Bio:           user.Bio,
+		Image:         user.Image,
+		EmailVerified: user.EmailVerified,
+		Private:       user.Private,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Token:         token,
 	}
 	responseJSON := model.UserResponseJSON{User: response}
 	w.Header().Set("Content-Type", "application/json")
@@ -270,16 +403,30 @@ func (h *Handlers) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.LogoutHandler"
 
 	uid := r.Context().Value("uid").(string)
-	token, err := h.UserRepository.FindTokenByUID(uid)
+	token := r.Context().Value("token").(string)
+
+	claims, err := security.ParseToken(token)
 	if err != nil {
-		h.log.Error(op+": failed to find token", "error", err, "uid", uid)
-		HandleError(w, "Failed to retrieve authentication token", http.StatusUnauthorized)
+		h.log.Error(op+": failed to decode token", "error", err, "uid", uid)
+		HandleError(w, "Invalid or expired token", http.StatusUnauthorized)
 		return
 	}
 
-	err = h.UserRepository.DeleteToken(token.Token)
+	if !h.audit(w, r, model.AuditLogout, model.AuditTargetUser, uid, nil) {
+		return
+	}
+
+	err = h.UserRepository.RevokeToken(claims.Jti, uid, claims.ExpiresAt)
 	if err != nil {
-		h.log.Error(op+": failed to delete token", "error", err, "token", token.Token)
+		h.log.Error(op+": failed to revoke token", "error", err, "uid", uid)
+		HandleError(w, "Failed to logout user", http.StatusInternalServerError)
+		return
+	}
+	h.Revocations.Revoke(claims.Jti, claims.ExpiresAt)
+
+	err = h.UserRepository.DeleteToken(token)
+	if err != nil && !errors.Is(err, repository.ErrTokenIsNotFound) {
+		h.log.Error(op+": failed to delete token", "error", err, "uid", uid)
 		HandleError(w, "Failed to logout user", http.StatusBadRequest)
 		return
 	}
@@ -287,3 +434,21 @@ func (h *Handlers) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	return
 }
+
+// rehashPassword upgrades a stored hash to the current argon2 parameters. The
+// login already succeeded, so failures are only logged.
+func (h *Handlers) rehashPassword(uid string, password string) {
+	const op = "handlers.rehashPassword"
+
+	passwd, err := security.GeneratePasswd(password)
+	if err != nil {
+		h.log.Error(op+": failed to hash password", "error", err, "uid", uid)
+		return
+	}
+	err = h.UserRepository.UpdatePassword(uid, passwd.Hash, passwd.Salt)
+	if err != nil {
+		h.log.Error(op+": failed to store rehashed password", "error", err, "uid", uid)
+		return
+	}
+	h.log.Info(op+": password rehashed", "uid", uid)
+}
@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional mail. Production deployments plug in an SMTP
+// or provider-backed implementation; LogMailer and FileMailer are meant for
+// local development.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type LogMailer struct {
+	from string
+	log  *slog.Logger
+}
+
+func NewLogMailer(from string, log *slog.Logger) *LogMailer {
+	return &LogMailer{from: from, log: log}
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	m.log.Info("mail sent", "op", "mailer.LogMailer.Send", "from", m.from, "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// FileMailer appends every message to a file, one block per message.
+type FileMailer struct {
+	from string
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileMailer(from string, path string) *FileMailer {
+	return &FileMailer{from: from, path: path}
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	const op = "mailer.FileMailer.Send"
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), m.from, msg.To, msg.Subject, msg.Body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// New picks an implementation by name; unknown names fall back to LogMailer.
+func New(kind string, from string, path string, log *slog.Logger) Mailer {
+	switch kind {
+	case "file":
+		return NewFileMailer(from, path)
+	default:
+		return NewLogMailer(from, log)
+	}
+}
@@ -0,0 +1,193 @@
+// Package markdown renders article bodies, CommonMark with GitHub's
+// strikethrough and bare-URL links, to sanitized HTML that is safe to embed.
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Version identifies the renderer output. Bump it whenever the HTML for the
+// same source changes so cached renderings get refreshed.
+const Version = 2
+
+// Goldmark and the HTML parser take time quadratic in how deeply blocks and
+// elements nest, and goldmark also in the number of emphasis and link
+// delimiters in one paragraph. Sources beyond these limits, which only
+// hostile input reaches, are shown as text instead.
+const (
+	maxDepth = 256
+	// maxDelimiters counts the characters of delimiterChars in a run of
+	// non-blank lines outside fenced code.
+	maxDelimiters  = 2048
+	delimiterChars = "*_~`[]<"
+)
+
+var (
+	converter = goldmark.New(
+		goldmark.WithExtensions(extension.Strikethrough, extension.Linkify),
+		// Raw HTML is passed through here and sanitized below.
+		goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+	)
+	policy = newPolicy()
+)
+
+// newPolicy extends bluemonday's user-generated-content policy, which drops
+// scripts, event handlers, styles and URLs with unsafe schemes.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireNoFollowOnLinks(true)
+	p.AllowElements("kbd", "mark")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	// Fenced code blocks name their language for client-side highlighting.
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	return p
+}
+
+// Render converts Markdown source into sanitized, well-formed HTML.
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\x00", "�")
+	if tooComplex(src) {
+		return fallback(src)
+	}
+	var buf bytes.Buffer
+	if err := converter.Convert([]byte(src), &buf); err != nil {
+		return fallback(src)
+	}
+	if depth(buf.String()) > maxDepth {
+		return fallback(src)
+	}
+	balanced, err := balance(buf.String())
+	if err != nil {
+		return fallback(src)
+	}
+	return policy.Sanitize(balanced)
+}
+
+// tooComplex reports whether src nests containers deeper than maxDepth or
+// has a paragraph with more than maxDelimiters delimiters. It runs before
+// goldmark, whose time grows quadratically with both.
+func tooComplex(src string) bool {
+	delimiters := 0
+	fence := ""
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			delimiters = 0
+			continue
+		}
+		if containerDepth(line) > maxDepth {
+			return true
+		}
+		for _, c := range line {
+			if strings.ContainsRune(delimiterChars, c) {
+				delimiters++
+			}
+		}
+		if delimiters > maxDelimiters {
+			return true
+		}
+	}
+	return false
+}
+
+// containerDepth counts the block quote and list markers that open line.
+func containerDepth(line string) int {
+	d := 0
+	for {
+		line = strings.TrimLeft(line, " \t")
+		switch {
+		case strings.HasPrefix(line, ">"):
+			line = line[1:]
+		case len(line) >= 2 && strings.ContainsRune("-+*", rune(line[0])) && (line[1] == ' ' || line[1] == '\t'):
+			line = line[2:]
+		default:
+			i := 0
+			for i < len(line) && i < 9 && line[i] >= '0' && line[i] <= '9' {
+				i++
+			}
+			if i == 0 || i+1 >= len(line) || (line[i] != '.' && line[i] != ')') || (line[i+1] != ' ' && line[i+1] != '\t') {
+				return d
+			}
+			line = line[i+2:]
+		}
+		d++
+	}
+}
+
+// depth returns how deeply the elements in s nest, counting unclosed ones as
+// still open. It is a cheap upper bound of what the parser will build.
+func depth(s string) int {
+	var open []string
+	deepest := 0
+	z := xhtml.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case xhtml.ErrorToken:
+			return deepest
+		case xhtml.StartTagToken:
+			name, _ := z.TagName()
+			if !voidElements[string(name)] {
+				open = append(open, string(name))
+				deepest = max(deepest, len(open))
+			}
+		case xhtml.EndTagToken:
+			name, _ := z.TagName()
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == string(name) {
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+}
+
+// voidElements never have an end tag.
+var voidElements = map[string]bool{"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true}
+
+// balance parses s as the content of a <body> and serializes it again,
+// which closes unclosed tags and fixes misnesting such as <b><em></b></em>
+// the way browsers do. Goldmark passes raw HTML through, so this runs before
+// bluemonday, which filters tags and attributes but does not repair
+// structure.
+func balance(s string) (string, error) {
+	body := &xhtml.Node{Type: xhtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := xhtml.ParseFragment(strings.NewReader(s), body)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		if err := xhtml.Render(&buf, n); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// fallback shows the source as preformatted text when it cannot be rendered,
+// for instance because it nests deeper than the HTML parser allows.
+func fallback(src string) string {
+	return "<pre>" + html.EscapeString(src) + "</pre>\n"
+}
@@ -0,0 +1,208 @@
+package markdown
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"heading", "# Hello *world*", "<h1>Hello <em>world</em></h1>\n"},
+		{"setext", "Title\n=====\n\nSub\n---", "<h1>Title</h1>\n<h2>Sub</h2>\n"},
+		{"paragraphs", "one\ntwo\n\nthree", "<p>one\ntwo</p>\n<p>three</p>\n"},
+		{"emphasis", "**bold** _em_ ~~del~~", "<p><strong>bold</strong> <em>em</em> <del>del</del></p>\n"},
+		{"nested emphasis", "*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>\n"},
+		{"intraword underscore", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"tight list", "- one\n- two\n  - nested", "<ul>\n<li>one</li>\n<li>two\n<ul>\n<li>nested</li>\n</ul>\n</li>\n</ul>\n"},
+		{"ordered list start", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"blockquote", "> quote\nlazy", "<blockquote>\n<p>quote\nlazy</p>\n</blockquote>\n"},
+		{"fenced code", "```go\nx := \"<y>\"\n```", "<pre><code class=\"language-go\">x := &#34;&lt;y&gt;&#34;\n</code></pre>\n"},
+		{"indented code", "    a < b\n", "<pre><code>a &lt; b\n</code></pre>\n"},
+		{"code span", "`<b>`", "<p><code>&lt;b&gt;</code></p>\n"},
+		{"thematic break", "a\n\n***\n\nb", "<p>a</p>\n<hr/>\n<p>b</p>\n"},
+		{"link", `[site](https://example.com "Title")`, `<p><a href="https://example.com" title="Title" rel="nofollow">site</a></p>` + "\n"},
+		{"relative link", "[up](../a?b=1&c=2)", `<p><a href="../a?b=1&amp;c=2" rel="nofollow">up</a></p>` + "\n"},
+		{"image", "![alt](https://example.com/a.png)", `<p><img src="https://example.com/a.png" alt="alt"/></p>` + "\n"},
+		{"autolinks", "<https://a.example/b> <me@example.com>", `<p><a href="https://a.example/b" rel="nofollow">https://a.example/b</a> <a href="mailto:me@example.com" rel="nofollow">me@example.com</a></p>` + "\n"},
+		{"allowed inline html", "<kbd>Ctrl</kbd>", "<p><kbd>Ctrl</kbd></p>\n"},
+	}
+	for _, c := range cases {
+		if got := Render(c.src); got != c.want {
+			t.Errorf("%s: Render(%q)\n got %q\nwant %q", c.name, c.src, got, c.want)
+		}
+	}
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"script tag", "<script>alert(1)</script>", ""},
+		{"style tag", "<style>p{}</style>hi", "hi"},
+		{"iframe", "<iframe src=x></iframe>", ""},
+		{"event handler", `<b onclick="alert(1)">x</b>`, "<p><b>x</b></p>\n"},
+		{"img handler", `<img src=x onerror=alert(1)>`, `<img src="x"/>`},
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"uppercase scheme", "[x](JaVaScRiPt:alert(1))", "<p>x</p>\n"},
+		{"entity in scheme", "[x](javascript&#58;alert(1))", "<p>x</p>\n"},
+		{"raw javascript link", `<a href="javascript:alert(1)">x</a>`, "<p>x</p>\n"},
+		{"data image", "![x](data:image/svg+xml;base64,PHN2Zz4=)", `<p><img alt="x"/></p>` + "\n"},
+		{"fence language", "```go\" onload=\"x\nx\n```", "<pre><code>x\n</code></pre>\n"},
+		{"misnested emphasis", "<b>*x</b>*", "<p><b><em>x</em></b></p>\n"},
+		{"unclosed tags", "<div><p>unclosed", "<div><p>unclosed</p></div>"},
+	}
+	for _, c := range cases {
+		if got := Render(c.src); got != c.want {
+			t.Errorf("%s: Render(%q)\n got %q\nwant %q", c.name, c.src, got, c.want)
+		}
+	}
+}
+
+func TestRenderDeepNesting(t *testing.T) {
+	for _, src := range []string{
+		strings.Repeat("> ", 30000),
+		strings.Repeat("<div>", 20000),
+		strings.Repeat("- ", 30000) + "x",
+		strings.Repeat("[", 30000) + strings.Repeat("*a ", 30000) + strings.Repeat("<b>", 30000),
+		strings.Repeat("[x](", 25000),
+		strings.Repeat("a_", 50000),
+	} {
+		start := time.Now()
+		out := Render(src)
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("Render(%.10q...) took %v", src, d)
+		}
+		if err := wellFormed(out); err != "" {
+			t.Errorf("Render(%.10q...): %s", src, err)
+		}
+	}
+}
+
+func TestTooComplex(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want bool
+	}{
+		{"plain", "# Title\n\nSome *text* with a [link](https://x).", false},
+		{"shallow nesting", strings.Repeat("> ", 10) + "- 1. x", false},
+		{"deep quotes", strings.Repeat(">", maxDepth+1) + " x", true},
+		{"deep lists", strings.Repeat("- ", maxDepth+1) + "x", true},
+		{"deep ordered lists", strings.Repeat("1) ", maxDepth+1) + "x", true},
+		{"delimiters split by blank lines", strings.Repeat(strings.Repeat("*", maxDelimiters)+"\n\n", 4), false},
+		{"delimiter flood", strings.Repeat("a_", maxDelimiters+1), true},
+		{"flood across lines", strings.Repeat("[x](\n", maxDelimiters), true},
+		{"fenced code is not counted", "```\n" + strings.Repeat("a_b ", maxDelimiters+1) + "\n```\n", false},
+		{"tilde fence", "~~~go\n" + strings.Repeat("*", maxDelimiters+1) + "\n~~~", false},
+	}
+	for _, c := range cases {
+		if got := tooComplex(c.src); got != c.want {
+			t.Errorf("%s: tooComplex = %v, want %v", c.name, got, c.want)
+		}
+	}
+	if got := Render("<b>" + strings.Repeat("a_", maxDelimiters)); got != "<pre>&lt;b&gt;"+strings.Repeat("a_", maxDelimiters)+"</pre>\n" {
+		t.Errorf("fallback = %.40q...", got)
+	}
+}
+
+// wellFormed checks that every tag in out is closed in order, returning a
+// description of the first problem or "".
+func wellFormed(out string) string {
+	var open []string
+	z := html.NewTokenizer(strings.NewReader(out))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return z.Err().Error()
+			}
+			if len(open) > 0 {
+				return "unclosed " + strings.Join(open, ", ")
+			}
+			return ""
+		case html.StartTagToken:
+			if name := z.Token().Data; !voidElements[name] {
+				open = append(open, name)
+			}
+		case html.EndTagToken:
+			name := z.Token().Data
+			if len(open) == 0 || open[len(open)-1] != name {
+				return "misplaced </" + name + "> with open " + strings.Join(open, ", ")
+			}
+			open = open[:len(open)-1]
+		}
+	}
+}
+
+func TestRenderWellFormed(t *testing.T) {
+	for _, src := range []string{
+		"<b>*x</b>*",
+		"*<i>a*</i>",
+		"**a <em>b** c</em>",
+		"[a <b>b](https://x)</b>",
+		"<ul><li>a<li>b",
+		"<p>a<div>b</p></div>",
+		"> <b>quote\n\nafter</b>",
+		"- <i>item\n- next</i>",
+		"<table><td>a</table>",
+		"</b></i>stray",
+	} {
+		out := Render(src)
+		if err := wellFormed(out); err != "" {
+			t.Errorf("Render(%q) = %q: %s", src, out, err)
+		}
+	}
+}
+
+var (
+	attrPattern = regexp.MustCompile(`(?i)<[^>]*\son[a-z]+\s*=`)
+	urlPattern  = regexp.MustCompile(`(?:href|src)="([^"]*)"`)
+)
+
+func FuzzRender(f *testing.F) {
+	for _, seed := range []string{
+		"# a\n\n*b* **c** `d`",
+		"- [x](http://a)\n- ![y](https://b)",
+		"<b onclick=x>y</b><script>z</script>",
+		"[a](javascript:b) [c](java&#115;cript:d)",
+		"> 1. ```\n>    <x>\n> ```",
+		"<a@b.c> <http://d> &amp; &#0; &#xFFFFFF;",
+		"<b>*x</b>*",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, src string) {
+		out := Render(src)
+		lower := strings.ToLower(out)
+		for _, bad := range []string{"<script", "<style", "<iframe", "<object", "<embed"} {
+			if strings.Contains(lower, bad) {
+				t.Fatalf("Render(%q) = %q: %s", src, out, bad)
+			}
+		}
+		if attrPattern.MatchString(out) {
+			t.Fatalf("Render(%q) = %q: event handler", src, out)
+		}
+		for _, m := range urlPattern.FindAllStringSubmatch(out, -1) {
+			u := strings.ToLower(html.UnescapeString(m[1]))
+			if i := strings.IndexAny(u, ":/?#"); i >= 0 && u[i] == ':' {
+				if s := u[:i]; s != "http" && s != "https" && s != "mailto" {
+					t.Fatalf("Render(%q) = %q: scheme %q", src, out, s)
+				}
+			}
+		}
+		if err := wellFormed(out); err != "" {
+			t.Fatalf("Render(%q) = %q: %s", src, out, err)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package model
+
+import "time"
+
+// DeletedUserID is the placeholder user that keeps anonymized articles of
+// deleted accounts.
+const DeletedUserID = "00000000-0000-0000-0000-000000000000"
+
+// What happens to a deleted user's articles.
+const (
+	ArticlesAnonymize = "anonymize"
+	ArticlesDelete    = "delete"
+)
+
+// DeleteAccountRequest confirms a deletion with the password or, for
+// accounts that sign in through an identity provider, a reauthToken from a
+// fresh OIDC re-authentication.
+type DeleteAccountRequest struct {
+	User struct {
+		Password    string `json:"password" validate:"required_without=ReauthToken"`
+		ReauthToken string `json:"reauthToken" validate:"required_without=Password"`
+	} `json:"user"`
+}
+
+// UserExport is everything stored about a user, as returned by the data
+// export endpoint. Secrets (password hash, TOTP secret, token values) are
+// left out. Tables that store per-user data must be added here.
+type UserExport struct {
+	ExportedAt             time.Time             `json:"exportedAt"`
+	User                   ExportedUser          `json:"user"`
+	Articles               []DBArticle           `json:"articles"`
+	ArticleRevisions       []ExportedRevision    `json:"articleRevisions"`
+	Following              []string              `json:"following"`
+	Followers              []string              `json:"followers"`
+	FollowRequestsSent     []ExportedRelation    `json:"followRequestsSent"`
+	FollowRequestsReceived []ExportedRelation    `json:"followRequestsReceived"`
+	Blocks                 []ExportedRelation    `json:"blocks"`
+	Mutes                  []ExportedRelation    `json:"mutes"`
+	FormerUsernames        []ExportedUsername    `json:"formerUsernames"`
+	Sessions               []ExportedSession     `json:"sessions"`
+	PersonalAccessTokens   []PersonalAccessToken `json:"personalAccessTokens"`
+	Identities             []ExportedIdentity    `json:"identities"`
+	AuditEvents            []AuditEvent          `json:"auditEvents"`
+}
+
+type ExportedUser struct {
+	ID               string     `json:"id"`
+	Username         string     `json:"username"`
+	Email            string     `json:"email"`
+	Bio              string     `json:"bio"`
+	Image            string     `json:"image"`
+	EmailVerified    bool       `json:"emailVerified"`
+	TwoFactorEnabled bool       `json:"twoFactorEnabled"`
+	Role             string     `json:"role"`
+	Private          bool       `json:"private"`
+	SuspendedAt      *time.Time `json:"suspendedAt"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+}
+
+type ExportedSession struct {
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type ExportedIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     *string   `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ExportedRelation is another user the exported user blocked, muted or
+// exchanged a follow request with.
+type ExportedRelation struct {
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ExportedUsername struct {
+	Username      string    `json:"username"`
+	ReleasedAt    time.Time `json:"releasedAt"`
+	ReservedUntil time.Time `json:"reservedUntil"`
+}
+
+// ExportedRevision is an article revision the exported user saved.
+type ExportedRevision struct {
+	Article string `json:"article"`
+	Revision
+}
+
+// OIDCState is a pending authorization code flow. ReauthUserID is set when
+// the flow confirms a signed-in user's identity rather than logging in.
+type OIDCState struct {
+	Nonce        string
+	Verifier     string
+	ReauthUserID string
+	CreatedAt    time.Time
+}
+
+type ReauthResponse struct {
+	ReauthToken string    `json:"reauthToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+type ReauthStartResponse struct {
+	URL string `json:"url"`
+}
@@ -1,6 +1,46 @@
 package model
 
-import "time"
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Article statuses. Drafts and scheduled articles are only visible to their
+// author; unlisted ones open by link but stay out of listings.
+const (
+	ArticleDraft     = "draft"
+	ArticleScheduled = "scheduled"
+	ArticlePublished = "published"
+	ArticleUnlisted  = "unlisted"
+)
+
+var ArticleStatuses = []string{ArticleDraft, ArticleScheduled, ArticlePublished, ArticleUnlisted}
+
+// Limits on article fields, in characters, and on the number of tags. The
+// handlers' validator registers them as the article_* validate tags used by
+// the article requests.
+const (
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 1000
+	MaxBodyLength        = 100000
+	MaxTags              = 10
+	MaxTagLength         = 32
+)
+
+// NormalizeTags trims and lowercases tags and drops repeats, keeping the
+// first occurrence's position. Tags left empty are kept so validation can
+// reject them.
+func NormalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
 
 type DBArticle struct {
 	Slug           string    `json:"slug"`
@@ -11,13 +51,38 @@ type DBArticle struct {
 	CreatedAt      time.Time `json:"createdAt"`
 	UpdatedAt      time.Time `json:"updatedAt"`
 	FavoritesCount int       `json:"favoritesCount"`
-	Author         string    `json:"author"`
+	Status         string    `json:"status"`
+	// PublishAt is when a scheduled article goes live.
+	PublishAt *time.Time `json:"publishAt"`
+	// Author is the author's current username; AuthorID is what the
+	// article row stores. AuthorBio and AuthorImage complete the author's
+	// profile for responses.
+	Author      string `json:"author"`
+	AuthorID    string `json:"-"`
+	AuthorBio   string `json:"-"`
+	AuthorImage string `json:"-"`
+	// BodyHTML is the sanitized rendering of Body, filled in on request.
+	BodyHTML string `json:"bodyHtml,omitempty"`
 }
 
+// Author is the profile of an article's author embedded in responses.
+// Following and FollowRequested are the viewer's follow state; Following
+// stays a plain boolean for clients that only know the RealWorld spec.
 type Author struct {
-	Username string `json:"username"`
-	Bio      string `json:"bio"`
+	ID              string `json:"-"`
+	Username        string `json:"username"`
+	Bio             string `json:"bio"`
+	Image           string `json:"image"`
+	Following       bool   `json:"following"`
+	FollowRequested bool   `json:"followRequested"`
+}
+
+// SetFollowState records the viewer's follow state towards the author.
+func (a *Author) SetFollowState(s FollowState) {
+	a.Following = s == FollowActive
+	a.FollowRequested = s == FollowPending
 }
+
 type DBArticleResponse struct {
 	Slug           string    `json:"slug"`
 	Title          string    `json:"title"`
@@ -33,10 +98,26 @@ type DBArticleResponse struct {
 
 type CreateArticleRequest struct {
 	Article struct {
-		Title       string   `json:"title"`
-		Description string   `json:"description"`
-		Body        string   `json:"body"`
-		TagList     []string `json:"tagList"`
+		Title       string   `json:"title" validate:"required,article_title"`
+		Description string   `json:"description" validate:"article_description"`
+		Body        string   `json:"body" validate:"required,article_body"`
+		TagList     []string `json:"tagList" validate:"article_tags,dive,tag"`
+		// Status defaults to published; scheduled needs PublishAt.
+		Status    string     `json:"status"`
+		PublishAt *time.Time `json:"publishAt"`
+	} `json:"article"`
+}
+
+// UpdateArticleRequest carries the fields to change; omitted fields keep
+// their value.
+type UpdateArticleRequest struct {
+	Article struct {
+		Title       *string    `json:"title" validate:"omitnil,min=1,article_title"`
+		Description *string    `json:"description" validate:"omitnil,article_description"`
+		Body        *string    `json:"body" validate:"omitnil,min=1,article_body"`
+		TagList     *[]string  `json:"tagList" validate:"omitnil,article_tags,dive,tag"`
+		Status      *string    `json:"status"`
+		PublishAt   *time.Time `json:"publishAt"`
 	} `json:"article"`
 }
 
@@ -50,18 +131,39 @@ type DBArticleResponseWithUsernameJsonWithoutCount struct {
 }
 
 type DBArticleResponseWithAuthorUsername struct {
-	Slug           string         `json:"slug"`
-	Title          string         `json:"title"`
-	Description    string         `json:"description"`
-	Body           string         `json:"body"`
-	TagList        []string       `json:"tagList"`
-	CreatedAt      time.Time      `json:"createdAt"`
-	UpdatedAt      time.Time      `json:"updatedAt"`
-	Favorited      bool           `json:"favorited"`
-	FavoritesCount int            `json:"favoritesCount"`
-	Author         AuthorUsername `json:"author"`
+	Slug           string     `json:"slug"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	Body           string     `json:"body"`
+	BodyHTML       string     `json:"bodyHtml,omitempty"`
+	TagList        []string   `json:"tagList"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	Favorited      bool       `json:"favorited"`
+	FavoritesCount int        `json:"favoritesCount"`
+	Status         string     `json:"status"`
+	PublishAt      *time.Time `json:"publishAt"`
+	Author         Author     `json:"author"`
+}
+
+// ArticleSearchResult is a search hit. Snippet is HTML: escaped text with
+// the matched words wrapped in <mark>.
+type ArticleSearchResult struct {
+	DBArticleResponseWithAuthorUsername
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type ArticleSearchResponse struct {
+	Articles      []ArticleSearchResult `json:"articles"`
+	ArticlesCount int                   `json:"articlesCount"`
 }
 
-type AuthorUsername struct {
-	Username string `json:"username"`
+// ArticleFilter narrows article listings; zero values match all. Listings
+// hold published articles plus, for ViewerID, their own unpublished ones;
+// ViewerID also hides articles by authors the viewer muted or blocked.
+type ArticleFilter struct {
+	Author   string
+	Tag      string
+	ViewerID string
 }
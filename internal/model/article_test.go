@@ -0,0 +1,23 @@
+package model
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	cases := []struct {
+		tags []string
+		want []string
+	}{
+		{nil, []string{}},
+		{[]string{" Go ", "go", "GO"}, []string{"go"}},
+		{[]string{"Rust", "golang", "rust", "Ёжик"}, []string{"rust", "golang", "ёжик"}},
+		{[]string{"", "  ", "a"}, []string{"", "a"}},
+	}
+	for _, c := range cases {
+		if got := NormalizeTags(c.tags); !slices.Equal(got, c.want) {
+			t.Errorf("NormalizeTags(%q) = %q, want %q", c.tags, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package model
+
+import "time"
+
+// Audit actions. Names are "<subject>.<verb>" and are stored as is, so they
+// must not be renamed once released.
+const (
+	AuditUserRegistered        = "user.registered"
+	AuditUserUpdated           = "user.updated"
+	AuditUserRenamed           = "user.renamed"
+	AuditUserDeleted           = "user.deleted"
+	AuditUserExported          = "user.exported"
+	AuditLoginSucceeded        = "login.succeeded"
+	AuditLoginFailed           = "login.failed"
+	AuditLoginChallenged       = "login.challenged"
+	AuditReauthenticated       = "login.reauthenticated"
+	AuditLogout                = "logout"
+	AuditPasswordChanged       = "password.changed"
+	AuditPasswordResetRequest  = "password.reset_requested"
+	AuditPasswordReset         = "password.reset"
+	AuditEmailVerified         = "email.verified"
+	AuditTOTPEnabled           = "totp.enabled"
+	AuditTOTPDisabled          = "totp.disabled"
+	AuditTokenCreated          = "token.created"
+	AuditTokenDeleted          = "token.deleted"
+	AuditFollowed              = "profile.followed"
+	AuditUnfollowed            = "profile.unfollowed"
+	AuditFollowRequestApproved = "follow_request.approved"
+	AuditFollowRequestRejected = "follow_request.rejected"
+	AuditBlocked               = "profile.blocked"
+	AuditUnblocked             = "profile.unblocked"
+	AuditAdminSuspended        = "admin.user_suspended"
+	AuditAdminUnsuspended      = "admin.user_unsuspended"
+	AuditAdminRoleChanged      = "admin.role_changed"
+	AuditAdminUnlocked         = "admin.user_unlocked"
+	AuditAdminArticleDeleted   = "admin.article_deleted"
+)
+
+// Audit target types.
+const (
+	AuditTargetUser    = "user"
+	AuditTargetToken   = "token"
+	AuditTargetArticle = "article"
+)
+
+type AuditEvent struct {
+	ID         int64          `json:"id"`
+	OccurredAt time.Time      `json:"occurredAt"`
+	ActorID    *string        `json:"actorId"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"targetType"`
+	TargetID   string         `json:"targetId"`
+	IP         string         `json:"ip"`
+	UserAgent  string         `json:"userAgent"`
+	Details    map[string]any `json:"details"`
+}
+
+// AuditFilter narrows audit queries; zero values match all. Subject matches
+// events the user performed plus anonymous events targeting them, such as
+// failed logins.
+type AuditFilter struct {
+	ActorID  string
+	Subject  string
+	Action   string
+	TargetID string
+	IP       string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+	Offset   int
+}
+
+type AuditEventsResponse struct {
+	Events      []AuditEvent `json:"events"`
+	EventsCount int          `json:"eventsCount"`
+}
@@ -11,3 +11,9 @@ func NewError(args ...string) *ErrorJson {
 	err.Errors.Body = args
 	return &err
 }
+
+// FieldErrorJson is the RealWorld error body keyed by the offending field,
+// e.g. {"errors": {"password": ["is too short"]}}.
+type FieldErrorJson struct {
+	Errors map[string][]string `json:"errors"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// FollowState is the viewer's follow status of a profile. Responses carry it
+// as the following and followRequested booleans.
+type FollowState int
+
+const (
+	FollowNone FollowState = iota
+	FollowActive
+	FollowPending
+)
+
+// FollowState reports the follow status the relationship implies.
+func (r Relationship) FollowState() FollowState {
+	switch {
+	case r.Following:
+		return FollowActive
+	case r.Requested:
+		return FollowPending
+	default:
+		return FollowNone
+	}
+}
+
+type FollowRequest struct {
+	Profile   Profile   `json:"profile"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type FollowRequestsResponse struct {
+	Requests []FollowRequest `json:"requests"`
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProfileSetFollowState(t *testing.T) {
+	cases := []struct {
+		state     FollowState
+		following bool
+		requested bool
+	}{
+		{FollowNone, false, false},
+		{FollowActive, true, false},
+		{FollowPending, false, true},
+	}
+	for _, c := range cases {
+		p := Profile{Following: true, FollowRequested: true}
+		p.SetFollowState(c.state)
+		if p.Following != c.following || p.FollowRequested != c.requested {
+			t.Errorf("state %d: following %v, requested %v", c.state, p.Following, p.FollowRequested)
+		}
+		got, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(got, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if fields["following"] != c.following || fields["followRequested"] != c.requested {
+			t.Errorf("state %d: json %s", c.state, got)
+		}
+	}
+}
+
+// Article authors keep following a boolean and report a pending request
+// separately.
+func TestAuthorSetFollowState(t *testing.T) {
+	cases := []struct {
+		state     FollowState
+		following bool
+		requested bool
+		json      string
+	}{
+		{FollowNone, false, false, `{"username":"","bio":"","image":"","following":false,"followRequested":false}`},
+		{FollowActive, true, false, `{"username":"","bio":"","image":"","following":true,"followRequested":false}`},
+		{FollowPending, false, true, `{"username":"","bio":"","image":"","following":false,"followRequested":true}`},
+	}
+	for _, c := range cases {
+		a := Author{Following: true, FollowRequested: true}
+		a.SetFollowState(c.state)
+		if a.Following != c.following || a.FollowRequested != c.requested {
+			t.Errorf("state %d: following %v, requested %v", c.state, a.Following, a.FollowRequested)
+		}
+		got, err := json.Marshal(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != c.json {
+			t.Errorf("state %d: json %s, want %s", c.state, got, c.json)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"rwa/internal/pkg"
+	"time"
+)
+
+// ArticleEdit describes who changes an article and how. NewBase, when set,
+// moves the article to a new slug allocated for that base; RestoredFrom
+// marks the new revision as a copy of an older one.
+type ArticleEdit struct {
+	EditorID     string
+	NewBase      string
+	RestoredFrom int
+}
+
+// Revision is one saved state of an article. Editor is empty when the
+// editing account no longer exists.
+type Revision struct {
+	Number       int       `json:"number"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Body         string    `json:"body,omitempty"`
+	TagList      []string  `json:"tagList"`
+	Editor       string    `json:"editor"`
+	RestoredFrom *int      `json:"restoredFrom"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// RenderedBody is the latest revision of an article with its cached HTML.
+// Version is the renderer version that produced HTML, zero when the
+// revision hasn't been rendered yet.
+type RenderedBody struct {
+	Revision int
+	Body     string
+	HTML     string
+	Version  int
+}
+
+type RevisionResponse struct {
+	Revision Revision `json:"revision"`
+}
+
+type RevisionsResponse struct {
+	Revisions      []Revision `json:"revisions"`
+	RevisionsCount int        `json:"revisionsCount"`
+}
+
+// FieldChange is a changed single-line field in a revision diff.
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// TagsChange lists tags only present on one side of a revision diff.
+type TagsChange struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// RevisionDiff compares two revisions. Unchanged fields are left out; Body
+// always holds the full line diff.
+type RevisionDiff struct {
+	From        int            `json:"from"`
+	To          int            `json:"to"`
+	Title       *FieldChange   `json:"title,omitempty"`
+	Description *FieldChange   `json:"description,omitempty"`
+	TagList     *TagsChange    `json:"tagList,omitempty"`
+	Body        []pkg.DiffLine `json:"body"`
+}
+
+type RevisionDiffResponse struct {
+	Diff RevisionDiff `json:"diff"`
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
+// Permissions checked by the admin endpoints.
+const (
+	PermissionUsersList      = "users:list"
+	PermissionUsersSuspend   = "users:suspend"
+	PermissionUsersUnlock    = "users:unlock"
+	PermissionUsersRole      = "users:role"
+	PermissionArticlesDelete = "articles:delete"
+	PermissionAuditRead      = "audit:read"
+)
+
+var rolePermissions = map[string][]string{
+	RoleUser:      {},
+	RoleModerator: {PermissionUsersList, PermissionArticlesDelete},
+	RoleAdmin: {
+		PermissionUsersList, PermissionUsersSuspend, PermissionUsersUnlock,
+		PermissionUsersRole, PermissionArticlesDelete, PermissionAuditRead,
+	},
+}
+
+// RoleHasPermission reports whether role grants permission. Unknown roles
+// grant nothing.
+func RoleHasPermission(role string, permission string) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// UserAccess is the part of a user checked on every authenticated request.
+type UserAccess struct {
+	Role        string
+	SuspendedAt *time.Time
+}
+
+type AdminUser struct {
+	ID            string     `json:"id"`
+	Username      string     `json:"username"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	EmailVerified bool       `json:"emailVerified"`
+	SuspendedAt   *time.Time `json:"suspendedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+type AdminUserResponse struct {
+	User AdminUser `json:"user"`
+}
+
+type AdminUsersResponse struct {
+	Users      []AdminUser `json:"users"`
+	UsersCount int         `json:"usersCount"`
+}
+
+// AdminUserFilter narrows the admin user listing; zero values match all.
+type AdminUserFilter struct {
+	Role      string
+	Suspended *bool
+	Query     string
+	Limit     int
+	Offset    int
+}
+
+type SetRoleRequest struct {
+	User struct {
+		Role string `json:"role" validate:"required,oneof=user moderator admin"`
+	} `json:"user"`
+}
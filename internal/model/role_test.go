@@ -0,0 +1,33 @@
+package model
+
+import "testing"
+
+func TestRoleHasPermission(t *testing.T) {
+	cases := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{RoleUser, PermissionUsersList, false},
+		{RoleUser, PermissionArticlesDelete, false},
+		{RoleModerator, PermissionUsersList, true},
+		{RoleModerator, PermissionArticlesDelete, true},
+		{RoleModerator, PermissionUsersSuspend, false},
+		{RoleModerator, PermissionUsersRole, false},
+		{RoleModerator, PermissionAuditRead, false},
+		{RoleAdmin, PermissionUsersList, true},
+		{RoleAdmin, PermissionUsersSuspend, true},
+		{RoleAdmin, PermissionUsersUnlock, true},
+		{RoleAdmin, PermissionUsersRole, true},
+		{RoleAdmin, PermissionArticlesDelete, true},
+		{RoleAdmin, PermissionAuditRead, true},
+		{RoleAdmin, "users:unknown", false},
+		{"", PermissionUsersList, false},
+		{"superuser", PermissionUsersList, false},
+	}
+	for _, c := range cases {
+		if got := RoleHasPermission(c.role, c.permission); got != c.want {
+			t.Errorf("RoleHasPermission(%q, %q) = %v, want %v", c.role, c.permission, got, c.want)
+		}
+	}
+}
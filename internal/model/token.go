@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Scopes a personal access token can be granted.
+const (
+	ScopeUserRead      = "user:read"
+	ScopeUserWrite     = "user:write"
+	ScopeProfileRead   = "profile:read"
+	ScopeProfileWrite  = "profile:write"
+	ScopeArticlesRead  = "articles:read"
+	ScopeArticlesWrite = "articles:write"
+)
+
+var Scopes = []string{ScopeUserRead, ScopeUserWrite, ScopeProfileRead, ScopeProfileWrite, ScopeArticlesRead, ScopeArticlesWrite}
+
+type PersonalAccessToken struct {
+	ID         string     `json:"id"`
+	UID        string     `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	// Token is only populated in the creation response.
+	Token string `json:"token,omitempty"`
+}
+
+type PersonalAccessTokenResponse struct {
+	Token PersonalAccessToken `json:"token"`
+}
+
+type PersonalAccessTokensResponse struct {
+	Tokens []PersonalAccessToken `json:"tokens"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Token struct {
+		Name      string     `json:"name" validate:"required,max=100"`
+		Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=user:read user:write profile:read profile:write articles:read articles:write"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	} `json:"token"`
+}
@@ -3,25 +3,34 @@ package model
 import "time"
 
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Bio       string    `json:"bio"`
-	Image     string    `json:"image"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Bio           string    `json:"bio"`
+	Image         string    `json:"image"`
+	EmailVerified bool      `json:"emailVerified"`
+	Private       bool      `json:"private"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
 }
 
 type UserTableDB struct {
-	ID           string    `json:"id,omitempty"`
-	Username     string    `json:"username,omitempty"`
-	Email        string    `json:"email,omitempty"`
-	PasswordHash string    `json:"password_hash,omitempty"`
-	PasswordSalt string    `json:"password_salt,omitempty"`
-	Bio          string    `json:"bio,omitempty"`
-	Image        string    `json:"image,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            string     `json:"id,omitempty"`
+	Username      string     `json:"username,omitempty"`
+	Email         string     `json:"email,omitempty"`
+	PasswordHash  string     `json:"password_hash,omitempty"`
+	PasswordSalt  string     `json:"password_salt,omitempty"`
+	Bio           string     `json:"bio,omitempty"`
+	Image         string     `json:"image,omitempty"`
+	EmailVerified bool       `json:"email_verified"`
+	TOTPSecret    string     `json:"-"`
+	TOTPEnabled   bool       `json:"totp_enabled"`
+	TOTPLastStep  int64      `json:"-"`
+	Role          string     `json:"role"`
+	SuspendedAt   *time.Time `json:"suspended_at"`
+	Private       bool       `json:"private"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 type UserAuthToken struct {
@@ -32,28 +41,78 @@ type UserAuthToken struct {
 	UID       string    `json:"uid"`
 }
 type UserResponse struct {
-	Id        string    `json:"id"`
-	Email     string    `json:"email"`
-	Username  string    `json:"username"`
-	Bio       string    `json:"bio"`
-	Image     string    `json:"image"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	Token     string    `json:"token"`
+	Id            string    `json:"id"`
+	Email         string    `json:"email"`
+	Username      string    `json:"username"`
+	Bio           string    `json:"bio"`
+	Image         string    `json:"image"`
+	EmailVerified bool      `json:"emailVerified"`
+	Private       bool      `json:"private"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	Token         string    `json:"token"`
 }
 type UserResponseJSON struct {
 	User UserResponse `json:"user"`
 }
+
+// Profile is a user as seen by the viewer. Following and FollowRequested are
+// the viewer's follow state, as on Author.
 type Profile struct {
-	Id        string    `json:"id"`
-	Username  string    `json:"username"`
-	Bio       string    `json:"bio"`
-	Image     string    `json:"image"`
-	Following bool      `json:"following"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	Id              string    `json:"id"`
+	Username        string    `json:"username"`
+	Bio             string    `json:"bio"`
+	Image           string    `json:"image"`
+	Following       bool      `json:"following"`
+	FollowRequested bool      `json:"followRequested"`
+	Blocking        bool      `json:"blocking"`
+	Muting          bool      `json:"muting"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// SetFollowState records the viewer's follow state towards the profile.
+func (p *Profile) SetFollowState(s FollowState) {
+	p.Following = s == FollowActive
+	p.FollowRequested = s == FollowPending
 }
 
 type ProfileResponse struct {
 	Profile Profile `json:"profile"`
 }
+
+type ProfilesResponse struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+// Relationship is how the viewer relates to another user.
+type Relationship struct {
+	Following bool
+	Requested bool
+	Blocking  bool
+	BlockedBy bool
+	Muting    bool
+}
+
+type LoginChallenge struct {
+	Token     string    `json:"token"`
+	Methods   []string  `json:"methods"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type LoginChallengeResponse struct {
+	Challenge LoginChallenge `json:"challenge"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
+type TOTPEnrollmentResponse struct {
+	TOTP TOTPEnrollment `json:"totp"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
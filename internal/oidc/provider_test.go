@@ -0,0 +1,169 @@
+package oidc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"rwa/internal/oidc"
+	"rwa/internal/oidc/oidctest"
+)
+
+const redirectURL = "http://app.test/auth/oidc/stub/callback"
+
+func newStub(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	t.Helper()
+	stub, err := oidctest.NewProvider("conduit", oidctest.User{
+		Subject:           "user-1",
+		Email:             "gopher@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "gopher",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(stub.Close)
+
+	p := oidc.NewProvider(oidc.Config{
+		Name:        "stub",
+		Issuer:      stub.Issuer(),
+		ClientID:    "conduit",
+		RedirectURL: redirectURL,
+	}, nil)
+	return stub, p
+}
+
+// authorize follows the authorization URL and returns the code and state the
+// stub redirects back with.
+func authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %d", resp.StatusCode)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	_, p := newStub(t)
+	ctx := context.Background()
+
+	verifier, err := oidc.GenerateVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, state := authorize(t, authURL)
+	if state != "state-1" {
+		t.Fatalf("state = %q", state)
+	}
+
+	claims, err := p.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "gopher@example.com" || !claims.EmailVerified || claims.PreferredUsername != "gopher" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestExchangeRejectsWrongVerifierAndNonce(t *testing.T) {
+	_, p := newStub(t)
+	ctx := context.Background()
+
+	verifier, _ := oidc.GenerateVerifier()
+	authURL, _ := p.AuthCodeURL(ctx, "s", "n", verifier)
+	code, _ := authorize(t, authURL)
+	other, _ := oidc.GenerateVerifier()
+	if _, err := p.Exchange(ctx, code, other, "n"); err == nil {
+		t.Error("exchange with wrong PKCE verifier succeeded")
+	}
+
+	authURL, _ = p.AuthCodeURL(ctx, "s", "n", verifier)
+	code, _ = authorize(t, authURL)
+	if _, err := p.Exchange(ctx, code, verifier, "other-nonce"); err == nil {
+		t.Error("exchange with wrong nonce succeeded")
+	}
+}
+
+// A discovery request that hangs must not hold up other logins with the same
+// provider.
+func TestSlowDiscoveryDoesNotBlock(t *testing.T) {
+	stub, _ := newStub(t)
+	release := make(chan struct{})
+	var first sync.Once
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stall := false
+		first.Do(func() { stall = true })
+		if stall {
+			<-release
+		}
+		resp, err := http.Get(stub.Issuer() + r.URL.Path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		var meta map[string]any
+		json.NewDecoder(resp.Body).Decode(&meta)
+		meta["issuer"] = "http://" + r.Host
+		json.NewEncoder(w).Encode(meta)
+	}))
+	defer proxy.Close()
+	defer close(release)
+
+	p := oidc.NewProvider(oidc.Config{Name: "stub", Issuer: proxy.URL, ClientID: "conduit", RedirectURL: redirectURL}, nil)
+	go p.AuthCodeURL(context.Background(), "s", "n", "v")
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := p.AuthCodeURL(ctx, "s", "n", "v"); err != nil {
+		t.Fatalf("second login waited on the stalled discovery: %v", err)
+	}
+}
+
+func TestReauthCodeURLForcesLogin(t *testing.T) {
+	_, p := newStub(t)
+	ctx := context.Background()
+
+	verifier, _ := oidc.GenerateVerifier()
+	authURL, err := p.ReauthCodeURL(ctx, "s", "n", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := u.Query(); q.Get("prompt") != "login" || q.Get("max_age") != "0" {
+		t.Errorf("reauth URL query = %v", q)
+	}
+
+	code, _ := authorize(t, authURL)
+	claims, err := p.Exchange(ctx, code, verifier, "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.AuthTime == 0 {
+		t.Error("auth_time missing from claims")
+	}
+}
@@ -0,0 +1,141 @@
+package pkg
+
+import "strings"
+
+// Line diff operations.
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffEdits bounds the Myers search, whose memory grows with the square
+// of the number of edits. Texts further apart than that are diffed as a
+// whole-block replacement of their differing middle.
+const maxDiffEdits = 1000
+
+// DiffLines returns a shortest edit script turning a into b, line by line,
+// using Myers' algorithm.
+func DiffLines(a, b string) []DiffLine {
+	x, y := splitLines(a), splitLines(b)
+
+	// Common prefix and suffix don't need the search.
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+
+	var out []DiffLine
+	for _, l := range x[:pre] {
+		out = append(out, DiffLine{DiffEqual, l})
+	}
+	out = append(out, myers(x[pre:len(x)-suf], y[pre:len(y)-suf])...)
+	for _, l := range x[len(x)-suf:] {
+		out = append(out, DiffLine{DiffEqual, l})
+	}
+	return out
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func myers(x, y []string) []DiffLine {
+	n, m := len(x), len(y)
+	off := n + m
+	if off == 0 {
+		return nil
+	}
+	// v[k+off] is the furthest x reached on diagonal k; trace keeps the
+	// diagonals -d..d of v before every step d for the backtrack.
+	v := make([]int, 2*off+2)
+	var trace [][]int
+	for d := 0; d <= off; d++ {
+		if d > maxDiffEdits {
+			return replaceAll(x, y)
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && v[k-1+off] < v[k+1+off]) {
+				i = v[k+1+off]
+			} else {
+				i = v[k-1+off] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			v[k+off] = i
+			if i >= n && j >= m {
+				return backtrack(x, y, trace, d)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(x, y []string, trace [][]int, d int) []DiffLine {
+	var rev []DiffLine
+	i, j := len(x), len(y)
+	for ; d > 0; d-- {
+		// trace[d] holds diagonals -d..d, so diagonal k is at k+d.
+		v := trace[d]
+		k := i - j
+		var prevK int
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevI := v[prevK+d]
+		prevJ := prevI - prevK
+		for i > prevI && j > prevJ {
+			i--
+			j--
+			rev = append(rev, DiffLine{DiffEqual, x[i]})
+		}
+		if i == prevI {
+			j--
+			rev = append(rev, DiffLine{DiffInsert, y[j]})
+		} else {
+			i--
+			rev = append(rev, DiffLine{DiffDelete, x[i]})
+		}
+	}
+	for i > 0 && j > 0 {
+		i--
+		j--
+		rev = append(rev, DiffLine{DiffEqual, x[i]})
+	}
+
+	out := make([]DiffLine, 0, len(rev))
+	for n := len(rev) - 1; n >= 0; n-- {
+		out = append(out, rev[n])
+	}
+	return out
+}
+
+func replaceAll(x, y []string) []DiffLine {
+	out := make([]DiffLine, 0, len(x)+len(y))
+	for _, l := range x {
+		out = append(out, DiffLine{DiffDelete, l})
+	}
+	for _, l := range y {
+		out = append(out, DiffLine{DiffInsert, l})
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+// apply rebuilds both sides of a diff.
+func apply(lines []DiffLine) (string, string) {
+	var a, b []string
+	for _, l := range lines {
+		if l.Op != DiffInsert {
+			a = append(a, l.Text)
+		}
+		if l.Op != DiffDelete {
+			b = append(b, l.Text)
+		}
+	}
+	return strings.Join(a, "\n"), strings.Join(b, "\n")
+}
+
+func TestDiffLines(t *testing.T) {
+	cases := []struct {
+		a, b    string
+		changes int
+	}{
+		{"", "", 0},
+		{"one\ntwo\nthree", "one\ntwo\nthree", 0},
+		{"", "one\ntwo", 2},
+		{"one\ntwo", "", 2},
+		{"one\ntwo\nthree", "one\n2\nthree", 2},
+		{"a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc", 5},
+		{"intro\nbody\noutro\n", "intro\nnew\nbody\noutro\n", 1},
+	}
+	for _, c := range cases {
+		got := DiffLines(c.a, c.b)
+		changes := 0
+		for _, l := range got {
+			if l.Op != DiffEqual {
+				changes++
+			}
+		}
+		if changes != c.changes {
+			t.Errorf("DiffLines(%q, %q) has %d changes, want %d: %v", c.a, c.b, changes, c.changes, got)
+		}
+		a, b := apply(got)
+		if a != strings.TrimSuffix(c.a, "\n") || b != strings.TrimSuffix(c.b, "\n") {
+			t.Errorf("DiffLines(%q, %q) = %v does not rebuild its inputs", c.a, c.b, got)
+		}
+	}
+}
+
+func TestDiffLinesFallsBackForLargeEdits(t *testing.T) {
+	var a, b []string
+	for i := 0; i < maxDiffEdits; i++ {
+		a = append(a, "a")
+		b = append(b, "b")
+	}
+	got := DiffLines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+	if len(got) != 2*maxDiffEdits {
+		t.Fatalf("got %d lines, want %d", len(got), 2*maxDiffEdits)
+	}
+	x, y := apply(got)
+	if x != strings.Join(a, "\n") || y != strings.Join(b, "\n") {
+		t.Errorf("fallback diff does not rebuild its inputs")
+	}
+}
@@ -1,21 +1,88 @@
 package pkg
 
 import (
-	"fmt"
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
 )
 
-func Slugify(title string, index int) string {
-	title = strings.ToLower(title)
-	title = strings.ReplaceAll(title, " ", "-")
-	title = strings.ReplaceAll(title, ".", "")
-	title = strings.ReplaceAll(title, ",", "")
-	title = strings.ReplaceAll(title, "!", "")
-	title = strings.ReplaceAll(title, "?", "")
-	title = strings.ReplaceAll(title, ":", "")
-	title = strings.ReplaceAll(title, ";", "")
-	title = strings.ReplaceAll(title, "'", "")
-	title = strings.ReplaceAll(title, "\"", "")
-	title = title + "-" + fmt.Sprintf("%d", index)
-	return title
+// MaxSlugLength bounds the title part of a slug; the "-<n>" suffix added by
+// the article storage comes on top of it.
+const MaxSlugLength = 80
+
+// fallbackSlug is used for titles with nothing transliterable in them, such
+// as emoji only.
+const fallbackSlug = "article"
+
+// transliteration covers letters that don't decompose into ASCII: Cyrillic
+// (Russian, Ukrainian, Belarusian, Serbian) and a few Latin ligatures and
+// stroked letters. Keys are lowercase.
+var transliteration = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u",
+	'ђ': "dj", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz",
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ł': "l",
+	'þ': "th", 'ð': "d", 'ı': "i",
+}
+
+// SlugBase turns title into the URL-safe part of a slug: lowercase ASCII
+// letters and digits separated by single dashes, at most MaxSlugLength bytes
+// and cut on a word boundary where possible.
+func SlugBase(title string) string {
+	var b strings.Builder
+	dash := false
+	word := func(s string) {
+		if s == "" {
+			return
+		}
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteString(s)
+	}
+
+	for _, r := range title {
+		r = unicode.ToLower(r)
+		if s, ok := transliteration[r]; ok {
+			word(s)
+			continue
+		}
+		// NFKD splits accented letters into a base letter plus combining
+		// marks, which are dropped.
+		for _, d := range norm.NFKD.String(string(r)) {
+			d = unicode.ToLower(d)
+			s, ok := transliteration[d]
+			switch {
+			case d >= 'a' && d <= 'z', d >= '0' && d <= '9':
+				word(string(d))
+			case ok:
+				word(s)
+			case unicode.Is(unicode.Mn, d):
+				// combining mark
+			case d == '\'' || d == '’':
+				// apostrophes join words: "don't" -> "dont"
+			default:
+				dash = true
+			}
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > MaxSlugLength {
+		slug = slug[:MaxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+		slug = strings.TrimSuffix(slug, "-")
+	}
+	if slug == "" {
+		return fallbackSlug
+	}
+	return slug
 }
@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSlugBase(t *testing.T) {
+	cases := []struct {
+		title string
+		want  string
+	}{
+		{"How to train your dragon", "how-to-train-your-dragon"},
+		{"  Hello,   World!!  ", "hello-world"},
+		{"Don't panic", "dont-panic"},
+		{"C# & Go / Rust #1", "c-go-rust-1"},
+		{"Привет, мир", "privet-mir"},
+		{"Щука и ёжик: Йошкар-Ола", "shchuka-i-ezhik-yoshkar-ola"},
+		{"Їжак і ґава", "yizhak-i-gava"},
+		{"Crème brûlée à la française", "creme-brulee-a-la-francaise"},
+		{"Straße", "strasse"},
+		{"Go 🚀 rocks 🎉", "go-rocks"},
+		{"🚀🎉", fallbackSlug},
+		{"", fallbackSlug},
+		{"a---b___c", "a-b-c"},
+	}
+	for _, c := range cases {
+		if got := SlugBase(c.title); got != c.want {
+			t.Errorf("SlugBase(%q) = %q, want %q", c.title, got, c.want)
+		}
+	}
+}
+
+func TestSlugBaseCutsOnWordBoundary(t *testing.T) {
+	title := strings.Repeat("word ", 30)
+	got := SlugBase(title)
+	if len(got) > MaxSlugLength {
+		t.Fatalf("len(%q) = %d, want at most %d", got, len(got), MaxSlugLength)
+	}
+	if !strings.HasSuffix(got, "-word") {
+		t.Errorf("SlugBase cut mid-word: %q", got)
+	}
+
+	long := strings.Repeat("x", MaxSlugLength+10)
+	if got := SlugBase(long); got != long[:MaxSlugLength] {
+		t.Errorf("single long word not truncated to %d: %q", MaxSlugLength, got)
+	}
+}
+
+func FuzzSlugBase(f *testing.F) {
+	for _, seed := range []string{"Hello World", "Привет, мир", "a/b#c&d?e", "🚀", "Ǆemal", "\x00\xff", " - "} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, title string) {
+		got := SlugBase(title)
+		if got == "" || len(got) > MaxSlugLength {
+			t.Fatalf("SlugBase(%q) = %q: bad length", title, got)
+		}
+		if !utf8.ValidString(got) || strings.HasPrefix(got, "-") || strings.HasSuffix(got, "-") || strings.Contains(got, "--") {
+			t.Fatalf("SlugBase(%q) = %q: bad separators", title, got)
+		}
+		for _, r := range got {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				t.Fatalf("SlugBase(%q) = %q: unsafe rune %q", title, got, r)
+			}
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"rwa/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// DeleteAccount removes a user. Depending on articles, their articles are
+// deleted or handed over to the placeholder user; follows, tokens and the
+// remaining per-user rows go with the user through ON DELETE CASCADE. The
+// username stays reserved until reservedUntil.
+func (s PostgresUserStorage) DeleteAccount(uid string, articles string, reservedUntil time.Time) error {
+	const op = "PostgresUserStorage.DeleteAccount"
+
+	if uid == model.DeletedUserID {
+		return errors.New("placeholder user cannot be deleted")
+	}
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var username string
+	err = tx.QueryRow(ctx, `SELECT username FROM users WHERE id = $1 FOR UPDATE`, uid).Scan(&username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		s.log.Error("failed to lock user", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to lock user")
+	}
+
+	if articles == model.ArticlesDelete {
+		_, err = tx.Exec(ctx, `DELETE FROM article WHERE author_id = $1`, uid)
+	} else {
+		_, err = tx.Exec(ctx, `UPDATE article SET author_id = $2 WHERE author_id = $1`, uid, model.DeletedUserID)
+	}
+	if err != nil {
+		s.log.Error("failed to release articles", slog.String("op", op), slog.String("policy", articles), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to release articles")
+	}
+
+	// The history row outlives the user: its user_id is set to NULL, which
+	// keeps the name reserved without redirecting anywhere.
+	if err := releaseUsername(ctx, tx, username, uid, reservedUntil); err != nil {
+		s.log.Error("failed to reserve username", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to reserve username")
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, uid)
+	if err != nil {
+		s.log.Error("failed to delete user", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to delete user")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.log.Info("account deleted", slog.String("op", op), slog.String("userID", uid), slog.String("policy", articles))
+	return nil
+}
+
+// ExportUser collects everything stored about a user from one snapshot.
+func (s PostgresUserStorage) ExportUser(uid string) (model.UserExport, error) {
+	const op = "PostgresUserStorage.ExportUser"
+
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserExport{}, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var u model.UserTableDB
+	err = scanUser(tx.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, uid), &u)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserExport{}, ErrUserNotFound
+		}
+		s.log.Error("failed to get user", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserExport{}, errors.Wrap(err, "failed to get user")
+	}
+
+	export := model.UserExport{
+		ExportedAt: time.Now().UTC(),
+		User: model.ExportedUser{
+			ID:               u.ID,
+			Username:         u.Username,
+			Email:            u.Email,
+			Bio:              u.Bio,
+			Image:            u.Image,
+			EmailVerified:    u.EmailVerified,
+			TwoFactorEnabled: u.TOTPEnabled,
+			Role:             u.Role,
+			Private:          u.Private,
+			SuspendedAt:      u.SuspendedAt,
+			CreatedAt:        u.CreatedAt,
+			UpdatedAt:        u.UpdatedAt,
+		},
+	}
+
+	export.Articles, err = collect(ctx, tx, `SELECT `+articleColumns+articleFrom+` WHERE a.author_id = $1 ORDER BY a.created_at`, []any{uid},
+		func(row pgx.Rows, a *model.DBArticle) error {
+			return row.Scan(&a.Slug, &a.Title, &a.Description, &a.Body, &a.TagList, &a.CreatedAt, &a.UpdatedAt, &a.FavoritesCount, &a.Status, &a.PublishAt, &a.Author)
+		})
+	if err != nil {
+		return model.UserExport{}, s.exportError(op, "articles", err)
+	}
+
+	export.ArticleRevisions, err = collect(ctx, tx, `SELECT r.article_slug, r.number, r.title, r.description, r.body, r.taglist, $2::text, r.restored_from, r.created_at
+		FROM article_revisions r WHERE r.editor_id = $1 ORDER BY r.created_at, r.id`, []any{uid, u.Username},
+		func(row pgx.Rows, r *model.ExportedRevision) error {
+			return row.Scan(&r.Article, &r.Number, &r.Title, &r.Description, &r.Body, &r.TagList, &r.Editor, &r.RestoredFrom, &r.CreatedAt)
+		})
+	if err != nil {
+		return model.UserExport{}, s.exportError(op, "article revisions", err)
+	}
+
+	scanName := func(row pgx.Rows, name *string) error { return row.Scan(name) }
+	export.Following, err = collect(ctx, tx, `SELECT u.username FROM subscriptions s JOIN users u ON u.id = s.target_user_id
+		WHERE s.sub_id = $1 ORDER BY u.username`, []any{uid}, scanName)
+	if err != nil {
+		return model.UserExport{}, s.exportError(op, "following", err)
+	}
+	export.Followers, err = collect(ctx, tx, `SELECT u.username FROM subscriptions s JOIN users u ON u.id = s.sub_id
+		WHERE s.target_user_id = $1 ORDER BY u.username`, []any{uid}, scanName)
+	if err != nil {
+		return model.UserExport{}, s.exportError(op, "followers", err)
+	}
+
+	scanRelation := func(row pgx.Rows, r *model.ExportedRelation) error { return row.Scan(&r.Username, &r.CreatedAt) }
+	relations := []struct {
+		section string
+		dst     *[]model.ExportedRelation
+		query   string
+	}{
+		{"follow requests sent", &export.FollowRequestsSent, `SELECT u.username, f.created_at FROM follow_requests f JOIN users u ON u.id = f.target_id
+			WHERE f.requester_id = $1 ORDER BY f.created_at`},
+		{"follow requests received", &export.FollowRequestsReceived, `SELECT u.username, f.created_at FROM follow_requests f JOIN users u ON u.id = f.requester_id
+			WHERE f.target_id = $1 ORDER BY f.created_at`},
+		{"blocks", &export.Blocks, `SELECT u.username, b.created_at FROM user_blocks b JOIN users u ON u.id = b.blocked_id
+			WHERE b.blocker_id = $1 ORDER BY b.created_at`},
+		{"mutes", &export.Mutes, `SELECT u.username, m.created_at FROM user_mutes m JOIN users u ON u.id = m.muted_id
+			WHERE m.muter_id = $1 ORDER BY m.created_at`},
+	}
+	for _, rel := range relations {
+		*rel.dst, err = collect(ctx, tx, rel.query, []any{uid}, scanRelation)
+		if err != nil {
+			return model.UserExport{}, s.exportError(op, rel.section, err)
+		}
+	}
+
+	export.FormerUsernames, err = collect(ctx, tx, `SELECT username, released_at, reserved_until FROM username_history WHERE user_id = $1 ORDER BY released_at`, []any{uid},
+		func(row pgx.Rows, n *model.ExportedUsername) error {
+			return row.Scan(&n.Username, &n.ReleasedAt, &n.ReservedUntil)
+		})
+	if err != nil {
+		return model.UserExport{}, s.exportError(op, "former usernames", err)
+	}
+
+	export.Sessions, err = collect(ctx, tx, `SELECT created_at, end_date FROM tokens WHERE user_id = $1 ORDER BY created_at`, []any{uid},
+		func(row pgx.Rows, t *model.ExportedSession) error { return row.Scan(&t.CreatedAt, &t.ExpiresAt) })
+	if err != nil {
+		return model.UserExport{}, s.exportError(op, "sessions", err)
+	}
+
+	export.PersonalAccessTokens, err = collect(ctx, tx, `SELECT id, user_id, name, scopes, created_at, last_used_at, expires_at
+		FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at`, []any{uid},
+		func(row pgx.Rows, t *model.PersonalAccessToken) error {
+			return row.Scan(&t.ID, &t.UID, &t.Name, &t.Scopes, &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt)
+		})
+	if err != nil {
+		return model.UserExport{}, s.exportError(op, "personal tokens", err)
+	}
+
+	export.Identities, err = collect(ctx, tx, `SELECT provider, subject, email, created_at FROM user_identities WHERE user_id = $1 ORDER BY created_at`, []any{uid},
+		func(row pgx.Rows, i *model.ExportedIdentity) error {
+			return row.Scan(&i.Provider, &i.Subject, &i.Email, &i.CreatedAt)
+		})
+	if err != nil {
+		return model.UserExport{}, s.exportError(op, "identities", err)
+	}
+
+	// The same events the user sees at GET /user/audit: their own actions
+	// and anonymous ones aimed at them, such as failed logins.
+	export.AuditEvents, err = collect(ctx, tx, `SELECT id, occurred_at, actor_id::text, action, target_type, target_id, ip, user_agent, details
+		FROM audit_log WHERE actor_id::text = $1 OR (actor_id IS NULL AND target_id = $1) ORDER BY id`, []any{uid},
+		func(row pgx.Rows, e *model.AuditEvent) error {
+			return row.Scan(&e.ID, &e.OccurredAt, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &e.IP, &e.UserAgent, &e.Details)
+		})
+	if err != nil {
+		return model.UserExport{}, s.exportError(op, "audit events", err)
+	}
+
+	return export, nil
+}
+
+func (s PostgresUserStorage) exportError(op string, section string, err error) error {
+	s.log.Error("failed to export user data", slog.String("op", op), slog.String("section", section), slog.String("error", err.Error()))
+	return errors.Wrap(err, "failed to export "+section)
+}
+
+// collect runs query and scans every row with scan. It returns an empty,
+// non-nil slice when there are no rows so exports encode [] instead of null.
+func collect[T any](ctx context.Context, tx pgx.Tx, query string, args []any, scan func(pgx.Rows, *T) error) ([]T, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		var item T
+		if err := scan(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"rwa/internal/model"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// GetUserAccess loads the role and suspension state checked on every
+// authenticated request.
+func (s PostgresUserStorage) GetUserAccess(uid string) (model.UserAccess, error) {
+	const op = "PostgresUserStorage.GetUserAccess"
+
+	query := `SELECT role, suspended_at FROM users WHERE id = $1`
+	ctx := context.Background()
+	var access model.UserAccess
+	err := s.db.QueryRow(ctx, query, uid).Scan(&access.Role, &access.SuspendedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserAccess{}, ErrUserNotFound
+		}
+		s.log.Error("failed to get user access", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserAccess{}, errors.Wrap(err, "failed to get user access")
+	}
+	return access, nil
+}
+
+// escapeLike quotes the LIKE wildcards in s, so a search for "a_b" does not
+// also match "axb". Backslash is the default LIKE escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ListUsers returns one page of users matching filter and the total number of
+// matches.
+func (s PostgresUserStorage) ListUsers(filter model.AdminUserFilter) ([]model.AdminUser, int, error) {
+	const op = "PostgresUserStorage.ListUsers"
+
+	var conds []string
+	var args []any
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		conds = append(conds, fmt.Sprintf("role = $%d", len(args)))
+	}
+	if filter.Suspended != nil {
+		if *filter.Suspended {
+			conds = append(conds, "suspended_at IS NOT NULL")
+		} else {
+			conds = append(conds, "suspended_at IS NULL")
+		}
+	}
+	if filter.Query != "" {
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+		conds = append(conds, fmt.Sprintf("(username ILIKE $%d OR email ILIKE $%d)", len(args), len(args)))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := `SELECT id, username, email, role, email_verified, suspended_at, created_at, count(*) OVER ()
+		FROM users` + where + fmt.Sprintf(` ORDER BY created_at, id LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	ctx := context.Background()
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		s.log.Error("failed to list users", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, 0, errors.Wrap(err, "failed to list users")
+	}
+	defer rows.Close()
+
+	users := []model.AdminUser{}
+	total := 0
+	for rows.Next() {
+		var u model.AdminUser
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.EmailVerified, &u.SuspendedAt, &u.CreatedAt, &total); err != nil {
+			s.log.Error("failed to scan user", slog.String("op", op), slog.String("error", err.Error()))
+			return nil, 0, errors.Wrap(err, "failed to scan user")
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.Wrap(err, "failed to read users")
+	}
+	return users, total, nil
+}
+
+// SetSuspended suspends or reinstates a user. Suspending an already
+// suspended user keeps the original timestamp.
+func (s PostgresUserStorage) SetSuspended(uid string, suspended bool) (model.AdminUser, error) {
+	const op = "PostgresUserStorage.SetSuspended"
+
+	query := `UPDATE users SET suspended_at = CASE WHEN $2 THEN COALESCE(suspended_at, now()) END, updated_at = now()
+		WHERE id = $1
+		RETURNING id, username, email, role, email_verified, suspended_at, created_at`
+	return s.updateAdminUser(op, query, uid, suspended)
+}
+
+func (s PostgresUserStorage) SetRole(uid string, role string) (model.AdminUser, error) {
+	const op = "PostgresUserStorage.SetRole"
+
+	query := `UPDATE users SET role = $2, updated_at = now()
+		WHERE id = $1
+		RETURNING id, username, email, role, email_verified, suspended_at, created_at`
+	return s.updateAdminUser(op, query, uid, role)
+}
+
+func (s PostgresUserStorage) updateAdminUser(op string, query string, args ...any) (model.AdminUser, error) {
+	ctx := context.Background()
+	var u model.AdminUser
+	err := s.db.QueryRow(ctx, query, args...).Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.EmailVerified, &u.SuspendedAt, &u.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AdminUser{}, ErrUserNotFound
+		}
+		s.log.Error("failed to update user", slog.String("op", op), slog.String("error", err.Error()))
+		return model.AdminUser{}, errors.Wrap(err, "failed to update user")
+	}
+
+	s.log.Info("user updated by admin", slog.String("op", op), slog.String("userID", u.ID), slog.String("role", u.Role))
+	return u, nil
+}
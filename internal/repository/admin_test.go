@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"rwa/internal/model"
+	"testing"
+)
+
+func TestEscapeLike(t *testing.T) {
+	cases := map[string]string{
+		"gopher":     "gopher",
+		"go_pher":    `go\_pher`,
+		"100%":       `100\%`,
+		`back\slash`: `back\\slash`,
+	}
+	for in, want := range cases {
+		if got := escapeLike(in); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestListUsersQueryIsLiteral(t *testing.T) {
+	s := NewPostgresUserStorage(testPool(t), testLog)
+	newTestUser(t, s)
+
+	// No username or email contains "%", so an unescaped wildcard would be
+	// the only way to match.
+	users, total, err := s.ListUsers(model.AdminUserFilter{Query: "%", Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 0 || len(users) != 0 {
+		t.Errorf("query %q matched %d users", "%", total)
+	}
+}
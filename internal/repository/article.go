@@ -6,21 +6,22 @@ import (
 	"fmt"
 	"log/slog"
 	"rwa/internal/model"
+	"strings"
 
+	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ArticleStorage interface {
-	CreateArticle(dbArticle model.DBArticle) error
+	CreateArticle(dbArticle model.DBArticle) (model.DBArticle, error)
 	DeleteArticle(slug string) error
-	GetArticles() ([]model.DBArticle, error)
-	GetArticlesByAuthor(authorUsername string) ([]model.DBArticle, error)
-	GetArticlesByTag(tag string) ([]model.DBArticle, error)
-	GetArticlesByAuthorAndTag(authorUsername string, tag string) ([]model.DBArticle, error)
+	ListArticles(filter model.ArticleFilter) ([]model.DBArticleResponseWithAuthorUsername, error)
+	FeedArticles(viewerID string, limit, offset int) ([]model.DBArticleResponseWithAuthorUsername, int, error)
 	GetArticleBySlug(slug string) (model.DBArticle, error)
-	UpdateArticle(article model.DBArticle) error
-	IsSlugExists(slug string) (bool, error)
+	UpdateArticle(slug string, article model.DBArticle, edit model.ArticleEdit) (model.DBArticle, error)
+	ResolveFormerSlug(slug string) (string, error)
 }
 
 type PostgresArticleStorage struct {
@@ -29,170 +30,337 @@ type PostgresArticleStorage struct {
 }
 
 const (
-	opIsSlugExists              = "repository.PostgresArticleStorage.IsSlugExists"
-	opCreateArticle             = "repository.PostgresArticleStorage.CreateArticle"
-	opDeleteArticle             = "repository.PostgresArticleStorage.DeleteArticle"
-	opGetArticles               = "repository.PostgresArticleStorage.GetArticles"
-	opGetArticlesByAuthor       = "repository.PostgresArticleStorage.GetArticlesByAuthor"
-	opGetArticlesByTag          = "repository.PostgresArticleStorage.GetArticlesByTag"
-	opGetArticlesByAuthorAndTag = "repository.PostgresArticleStorage.GetArticlesByAuthorAndTag"
-	opGetArticleBySlug          = "repository.PostgresArticleStorage.GetArticleBySlug"
-	opUpdateArticle             = "repository.PostgresArticleStorage.UpdateArticle"
+	opCreateArticle     = "repository.PostgresArticleStorage.CreateArticle"
+	opDeleteArticle     = "repository.PostgresArticleStorage.DeleteArticle"
+	opListArticles      = "repository.PostgresArticleStorage.ListArticles"
+	opFeedArticles      = "repository.PostgresArticleStorage.FeedArticles"
+	opGetArticleBySlug  = "repository.PostgresArticleStorage.GetArticleBySlug"
+	opUpdateArticle     = "repository.PostgresArticleStorage.UpdateArticle"
+	opResolveFormerSlug = "repository.PostgresArticleStorage.ResolveFormerSlug"
+	opPublishDue        = "repository.PostgresArticleStorage.PublishDue"
+	opSearchArticles    = "repository.PostgresArticleStorage.SearchArticles"
 )
 
-func (p PostgresArticleStorage) IsSlugExists(slug string) bool {
-	const op = opIsSlugExists
-	query := `SELECT slug FROM article WHERE slug = $1`
-	ctx := context.Background()
-	row := p.db.QueryRow(ctx, query, slug)
-	var dbSlug string
-	err := row.Scan(&dbSlug)
-	if err != nil {
-		p.log.Error("failed to check if slug exists", "op", op, "slug", slug, "error", err)
+// articleColumns selects an article joined with its author (aliased a and u)
+// in the order the scans below expect; the last column is the author's
+// current username.
+const articleColumns = `a.slug, a.title, a.description, a.body, a.taglist, a.created_at, a.updated_at, a.favoritesCount, a.status, a.publish_at, u.username`
 
-		return !(errors.Is(err, pgx.ErrNoRows))
-	}
-	return true
+const articleFrom = ` FROM article a JOIN users u ON u.id = a.author_id`
+
+// authorColumns complete the author's profile after articleColumns.
+const authorColumns = `COALESCE(u.bio, ''), COALESCE(u.image, ''), a.author_id`
+
+// scanArticle reads a row of articleColumns followed by authorColumns.
+func scanArticle(row pgx.Row) (model.DBArticle, error) {
+	var article model.DBArticle
+	err := row.Scan(&article.Slug, &article.Title, &article.Description, &article.Body, &article.TagList, &article.CreatedAt, &article.UpdatedAt, &article.FavoritesCount, &article.Status, &article.PublishAt, &article.Author, &article.AuthorBio, &article.AuthorImage, &article.AuthorID)
+	return article, err
 }
 
 func NewPostgresArticleStorage(db *pgxpool.Pool, log *slog.Logger) *PostgresArticleStorage {
 	return &PostgresArticleStorage{db: db, log: log}
 }
 
-func (p PostgresArticleStorage) CreateArticle(dbArticle model.DBArticle) error {
+// maxSlugAttempts bounds how often a slug allocation is retried after losing
+// a race for the same slug to a concurrent insert or rename.
+const maxSlugAttempts = 5
+
+// nextSlug is an SQL expression for the first free slug for the base in $1:
+// the base plus one more than the highest "-<n>" suffix among current and
+// former slugs, so a retired slug is never handed to another article. Bases
+// only contain [a-z0-9-], so they are safe inside both patterns; the LIKE
+// prefix lets the text_pattern_ops indexes narrow the scan.
+const nextSlug = `(
+	SELECT $1 || '-' || (COALESCE(MAX(substring(taken.slug FROM '-([0-9]+)$')::bigint), 0) + 1)
+	FROM (SELECT slug FROM article UNION ALL SELECT slug FROM article_slug_history) taken
+	WHERE taken.slug LIKE $1 || '-%' AND taken.slug ~ ('^' || $1 || '-[0-9]+$'))`
+
+// allocateSlug runs attempt until it stops failing on a concurrent insert of
+// the same slug, at most maxSlugAttempts times.
+func (p PostgresArticleStorage) allocateSlug(op string, base string, attempt func() error) error {
+	for i := 1; i <= maxSlugAttempts; i++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "article_pkey" {
+			p.log.Warn("slug taken by concurrent insert, retrying", "op", op, "base", base, "attempt", i)
+			continue
+		}
+		return err
+	}
+	p.log.Error("gave up allocating slug", "op", op, "base", base, "attempts", maxSlugAttempts)
+	return ErrSlugUnavailable
+}
+
+// CreateArticle inserts dbArticle with its first revision and returns the
+// stored article. dbArticle.Slug is the base produced by pkg.SlugBase; the
+// stored slug is that base plus the next free "-<n>" suffix, picked by the
+// insert itself. The database sets the creation and update times.
+func (p PostgresArticleStorage) CreateArticle(dbArticle model.DBArticle) (model.DBArticle, error) {
 	const op = opCreateArticle
-	query := `INSERT INTO article (slug, title, description, body, taglist, created_at, updated_at, author) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	query := `
+		WITH a AS (
+			INSERT INTO article (slug, title, description, body, taglist, author_id, status, publish_at)
+			VALUES (` + nextSlug + `, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING *
+		), revision AS (
+			INSERT INTO article_revisions (article_slug, number, editor_id, title, description, body, taglist, created_at)
+			SELECT slug, 1, author_id, title, description, body, taglist, created_at FROM a
+		)
+		SELECT ` + articleColumns + `, ` + authorColumns + ` FROM a JOIN users u ON u.id = a.author_id`
 	ctx := context.Background()
-	_, err := p.db.Exec(ctx, query, dbArticle.Slug, dbArticle.Title, dbArticle.Description, dbArticle.Body, dbArticle.TagList, dbArticle.CreatedAt, dbArticle.UpdatedAt, dbArticle.Author)
+	var created model.DBArticle
+	err := p.allocateSlug(op, dbArticle.Slug, func() error {
+		var err error
+		created, err = scanArticle(p.db.QueryRow(ctx, query, dbArticle.Slug, dbArticle.Title, dbArticle.Description, dbArticle.Body, dbArticle.TagList, dbArticle.AuthorID, dbArticle.Status, dbArticle.PublishAt))
+		return err
+	})
+	if errors.Is(err, ErrSlugUnavailable) {
+		return model.DBArticle{}, err
+	}
 	if err != nil {
-		p.log.Error("failed to create article", "op", op, "slug", dbArticle.Slug, "error", err)
-		return fmt.Errorf("%s: %w", op, err)
+		p.log.Error("failed to create article", "op", op, "base", dbArticle.Slug, "error", err)
+		return model.DBArticle{}, fmt.Errorf("%s: %w", op, err)
 	}
-	return nil
+	return created, nil
 }
 
 func (p PostgresArticleStorage) DeleteArticle(slug string) error {
 	const op = opDeleteArticle
 	query := `DELETE FROM article WHERE slug = $1`
 	ctx := context.Background()
-	_, err := p.db.Exec(ctx, query, slug)
+	result, err := p.db.Exec(ctx, query, slug)
 	if err != nil {
 		p.log.Error("failed to delete article", "op", op, "slug", slug, "error", err)
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	if result.RowsAffected() == 0 {
+		return ErrArticleNotFound
+	}
 	return nil
 }
 
-func (p PostgresArticleStorage) GetArticles() ([]model.DBArticleResponseWithAuthorUsername, error) {
-	const op = opGetArticles
-	query := `SELECT * FROM article`
+// articleConds turns filter into WHERE conditions over articleFrom and
+// their arguments.
+func articleConds(filter model.ArticleFilter) ([]string, []any) {
+	var conds []string
+	var args []any
+	if filter.Author != "" {
+		args = append(args, filter.Author)
+		conds = append(conds, fmt.Sprintf("u.username = $%d", len(args)))
+	}
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		conds = append(conds, fmt.Sprintf("a.taglist @> ARRAY[$%d]", len(args)))
+	}
+	args = append(args, model.ArticlePublished)
+	if filter.ViewerID == "" {
+		conds = append(conds, fmt.Sprintf("a.status = $%d", len(args)))
+	} else {
+		args = append(args, filter.ViewerID)
+		n := len(args)
+		conds = append(conds,
+			fmt.Sprintf("(a.status = $%d OR a.author_id = $%d)", n-1, n),
+			fmt.Sprintf("NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.muter_id = $%d AND m.muted_id = a.author_id)", n),
+			fmt.Sprintf("NOT EXISTS (SELECT 1 FROM user_blocks b WHERE b.blocker_id = $%d AND b.blocked_id = a.author_id)", n))
+	}
+	return conds, args
+}
+
+// ListArticles returns the articles matching filter.
+func (p PostgresArticleStorage) ListArticles(filter model.ArticleFilter) ([]model.DBArticleResponseWithAuthorUsername, error) {
+	const op = opListArticles
+
+	conds, args := articleConds(filter)
+	query := `SELECT ` + articleColumns + `, ` + authorColumns + articleFrom + ` WHERE ` + strings.Join(conds, " AND ")
+
 	ctx := context.Background()
-	rows, err := p.db.Query(ctx, query)
+	rows, err := p.db.Query(ctx, query, args...)
 	if err != nil {
-		p.log.Error("failed to get articles", "op", op, "error", err)
+		p.log.Error("failed to get articles", "op", op, "author", filter.Author, "tag", filter.Tag, "error", err)
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer rows.Close()
+
 	var articles []model.DBArticleResponseWithAuthorUsername
 	for rows.Next() {
 		var article model.DBArticleResponseWithAuthorUsername
-		err := rows.Scan(&article.Slug, &article.Title, &article.Description, &article.Body, &article.TagList, &article.CreatedAt, &article.UpdatedAt, &article.FavoritesCount, &article.Author.Username)
+		err = rows.Scan(&article.Slug, &article.Title, &article.Description, &article.Body, &article.TagList, &article.CreatedAt, &article.UpdatedAt, &article.FavoritesCount, &article.Status, &article.PublishAt, &article.Author.Username, &article.Author.Bio, &article.Author.Image, &article.Author.ID)
 		if err != nil {
 			p.log.Error("failed to scan article", "op", op, "error", err)
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 		articles = append(articles, article)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 	return articles, nil
 }
 
-func (p PostgresArticleStorage) GetArticlesByAuthor(authorUsername string) ([]model.DBArticleResponseWithAuthorUsername, error) {
-	const op = opGetArticlesByAuthor
-	query := `SELECT * FROM article WHERE author = $1`
+// FeedArticles returns one page of the published articles by authors
+// viewerID follows, newest first, and the total number of them. Muted and
+// blocked authors are left out as in listings.
+func (p PostgresArticleStorage) FeedArticles(viewerID string, limit, offset int) ([]model.DBArticleResponseWithAuthorUsername, int, error) {
+	const op = opFeedArticles
+
+	conds, args := articleConds(model.ArticleFilter{ViewerID: viewerID})
+	// The viewer's own drafts pass articleConds but do not belong here.
+	args = append(args, model.ArticlePublished, viewerID)
+	conds = append(conds,
+		fmt.Sprintf("a.status = $%d", len(args)-1),
+		fmt.Sprintf("EXISTS (SELECT 1 FROM subscriptions s WHERE s.sub_id = $%d AND s.target_user_id = a.author_id)", len(args)))
+	args = append(args, limit, offset)
+	query := `SELECT ` + articleColumns + `, ` + authorColumns + `, count(*) OVER ()` + articleFrom + ` WHERE ` + strings.Join(conds, " AND ") +
+		fmt.Sprintf(` ORDER BY a.created_at DESC, a.slug LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
 	ctx := context.Background()
-	rows, err := p.db.Query(ctx, query, authorUsername)
+	rows, err := p.db.Query(ctx, query, args...)
 	if err != nil {
-		p.log.Error("failed to get articles by author", "op", op, "author", authorUsername, "error", err)
-		return nil, fmt.Errorf("%s: %w", op, err)
+		p.log.Error("failed to get feed", "op", op, "error", err)
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
 	}
-	var articles []model.DBArticleResponseWithAuthorUsername
+	defer rows.Close()
+
+	articles := []model.DBArticleResponseWithAuthorUsername{}
+	total := 0
 	for rows.Next() {
 		var article model.DBArticleResponseWithAuthorUsername
-		err = rows.Scan(&article.Slug, &article.Title, &article.Description, &article.Body, &article.TagList, &article.CreatedAt, &article.UpdatedAt, &article.FavoritesCount, &article.Author.Username)
+		err = rows.Scan(&article.Slug, &article.Title, &article.Description, &article.Body, &article.TagList, &article.CreatedAt, &article.UpdatedAt, &article.FavoritesCount, &article.Status, &article.PublishAt, &article.Author.Username, &article.Author.Bio, &article.Author.Image, &article.Author.ID, &total)
 		if err != nil {
-			p.log.Error("failed to scan article", "op", op, "author", authorUsername, "error", err)
-			return nil, fmt.Errorf("%s: %w", op, err)
+			p.log.Error("failed to scan article", "op", op, "error", err)
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
 		}
 		articles = append(articles, article)
 	}
-	return articles, nil
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return articles, total, nil
 }
 
-func (p PostgresArticleStorage) GetArticlesByTag(tag string) ([]model.DBArticleResponseWithAuthorUsername, error) {
-	const op = opGetArticlesByTag
-	query := `SELECT * FROM article WHERE taglist @> ARRAY[$1]`
+func (p PostgresArticleStorage) GetArticleBySlug(slug string) (model.DBArticle, error) {
+	const op = opGetArticleBySlug
+	query := `SELECT ` + articleColumns + `, ` + authorColumns + articleFrom + ` WHERE a.slug = $1`
 	ctx := context.Background()
-	rows, err := p.db.Query(ctx, query, tag)
+	article, err := scanArticle(p.db.QueryRow(ctx, query, slug))
 	if err != nil {
-		p.log.Error("failed to get articles by tag", "op", op, "tag", tag, "error", err)
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
-	var articles []model.DBArticleResponseWithAuthorUsername
-	for rows.Next() {
-		var article model.DBArticleResponseWithAuthorUsername
-		err = rows.Scan(&article.Slug, &article.Title, &article.Description, &article.Body, &article.TagList, &article.CreatedAt, &article.UpdatedAt, &article.FavoritesCount, &article.Author.Username)
-		if err != nil {
-			p.log.Error("failed to scan article", "op", op, "tag", tag, "error", err)
-			return nil, fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.DBArticle{}, ErrArticleNotFound
 		}
-		articles = append(articles, article)
+		p.log.Error("failed to get article by slug", "op", op, "slug", slug, "error", err)
+		return model.DBArticle{}, fmt.Errorf("%s: %w", op, err)
 	}
-	return articles, nil
+	return article, nil
 }
 
-func (p PostgresArticleStorage) GetArticlesByAuthorAndTag(authorUsername string, tag string) ([]model.DBArticleResponseWithAuthorUsername, error) {
-	const op = opGetArticlesByAuthorAndTag
-	query := `SELECT * FROM article WHERE author = $1 AND taglist @> ARRAY[$2]`
+// ResolveFormerSlug returns the current slug of the article that used to be
+// reachable under slug, or ErrArticleNotFound.
+func (p PostgresArticleStorage) ResolveFormerSlug(slug string) (string, error) {
+	const op = opResolveFormerSlug
+	query := `SELECT article_slug FROM article_slug_history WHERE slug = $1`
 	ctx := context.Background()
-	rows, err := p.db.Query(ctx, query, authorUsername, tag)
+	var current string
+	err := p.db.QueryRow(ctx, query, slug).Scan(&current)
 	if err != nil {
-		p.log.Error("failed to get articles by author and tag", "op", op, "author", authorUsername, "tag", tag, "error", err)
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
-	var articles []model.DBArticleResponseWithAuthorUsername
-	for rows.Next() {
-		var article model.DBArticleResponseWithAuthorUsername
-		err = rows.Scan(&article.Slug, &article.Title, &article.Description, &article.Body, &article.TagList, &article.CreatedAt, &article.UpdatedAt, &article.FavoritesCount, &article.Author.Username)
-		if err != nil {
-			p.log.Error("failed to scan article", "op", op, "author", authorUsername, "tag", tag, "error", err)
-			return nil, fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrArticleNotFound
 		}
-		articles = append(articles, article)
+		p.log.Error("failed to resolve former slug", "op", op, "slug", slug, "error", err)
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	return articles, nil
+	return current, nil
 }
 
-func (p PostgresArticleStorage) GetArticleBySlug(slug string) (model.DBArticle, error) {
-	const op = opGetArticleBySlug
-	query := `SELECT * FROM article WHERE slug = $1`
+// UpdateArticle saves the editable fields of article under slug as a new
+// revision and returns the stored article. When edit.NewBase is set the
+// article moves to a freshly allocated slug for it, and slug is kept in the
+// history so GetArticleBySlug callers can redirect. The database sets the
+// update time.
+func (p PostgresArticleStorage) UpdateArticle(slug string, article model.DBArticle, edit model.ArticleEdit) (model.DBArticle, error) {
+	const op = opUpdateArticle
 	ctx := context.Background()
-	row := p.db.QueryRow(ctx, query, slug)
-	var article model.DBArticle
-	err := row.Scan(&article.Slug, &article.Title, &article.Description, &article.Body, &article.TagList, &article.CreatedAt, &article.UpdatedAt, &article.FavoritesCount, &article.Author)
+
+	// The update locks the article row, which serialises concurrent edits
+	// and so the revision numbers.
+	query := `UPDATE article SET title = $2, description = $3, body = $4, taglist = $5, updated_at = now(), status = $6, publish_at = $7 WHERE slug = $1 RETURNING slug`
+	args := []any{slug, article.Title, article.Description, article.Body, article.TagList, article.Status, article.PublishAt}
+	if edit.NewBase != "" {
+		query = `UPDATE article SET slug = ` + nextSlug + `, title = $3, description = $4, body = $5, taglist = $6, updated_at = now(), status = $7, publish_at = $8 WHERE slug = $2 RETURNING slug`
+		args = append([]any{edit.NewBase}, args...)
+	}
+	var restoredFrom *int
+	if edit.RestoredFrom > 0 {
+		restoredFrom = &edit.RestoredFrom
+	}
+	var editorID *string
+	if edit.EditorID != "" {
+		editorID = &edit.EditorID
+	}
+
+	var current string
+	var updated model.DBArticle
+	err := p.allocateSlug(op, edit.NewBase, func() error {
+		tx, err := p.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		err = tx.QueryRow(ctx, query, args...).Scan(&current)
+		if err != nil {
+			return err
+		}
+		if current != slug {
+			_, err = tx.Exec(ctx, `INSERT INTO article_slug_history (slug, article_slug) VALUES ($1, $2)`, slug, current)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO article_revisions (article_slug, number, editor_id, title, description, body, taglist, restored_from)
+			SELECT $1, COALESCE(MAX(number), 0) + 1, $2, $3, $4, $5, $6, $7 FROM article_revisions WHERE article_slug = $1`,
+			current, editorID, article.Title, article.Description, article.Body, article.TagList, restoredFrom)
+		if err != nil {
+			return err
+		}
+		updated, err = scanArticle(tx.QueryRow(ctx, `SELECT `+articleColumns+`, `+authorColumns+articleFrom+` WHERE a.slug = $1`, current))
+		if err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.DBArticle{}, ErrArticleNotFound
+	}
+	if errors.Is(err, ErrSlugUnavailable) {
+		return model.DBArticle{}, err
+	}
 	if err != nil {
-		p.log.Error("failed to get article by slug", "op", op, "slug", slug, "error", err)
+		p.log.Error("failed to update article", "op", op, "slug", slug, "error", err)
 		return model.DBArticle{}, fmt.Errorf("%s: %w", op, err)
 	}
-	return article, nil
+	return updated, nil
 }
 
-func (p PostgresArticleStorage) UpdateArticle(article model.DBArticle) error {
-	const op = opUpdateArticle
-	query := `UPDATE article SET title = $1, description = $2, body = $3, taglist = $4, updated_at = $5 WHERE slug = $6`
+// PublishDue publishes the scheduled articles whose time has come and
+// returns their slugs.
+func (p PostgresArticleStorage) PublishDue() ([]string, error) {
+	const op = opPublishDue
+	query := `UPDATE article SET status = $1 WHERE status = $2 AND publish_at <= now() RETURNING slug`
 	ctx := context.Background()
-	_, err := p.db.Exec(ctx, query, article.Title, article.Description, article.Body, article.TagList, article.UpdatedAt, article.Slug)
+	rows, err := p.db.Query(ctx, query, model.ArticlePublished, model.ArticleScheduled)
 	if err != nil {
-		p.log.Error("failed to update article", "op", op, "slug", article.Slug, "error", err)
-		return fmt.Errorf("%s: %w", op, err)
+		p.log.Error("failed to publish scheduled articles", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return nil
+	slugs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		p.log.Error("failed to publish scheduled articles", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return slugs, nil
 }
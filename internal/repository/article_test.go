@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"rwa/internal/model"
+)
+
+func TestArticleConds(t *testing.T) {
+	published := model.ArticlePublished
+	tests := []struct {
+		name      string
+		filter    model.ArticleFilter
+		wantConds []string
+		wantArgs  []any
+	}{
+		{
+			name:      "anonymous",
+			wantConds: []string{"a.status = $1"},
+			wantArgs:  []any{published},
+		},
+		{
+			name:      "author and tag",
+			filter:    model.ArticleFilter{Author: "gopher", Tag: "go"},
+			wantConds: []string{"u.username = $1", "a.taglist @> ARRAY[$2]", "a.status = $3"},
+			wantArgs:  []any{"gopher", "go", published},
+		},
+		{
+			name:   "viewer",
+			filter: model.ArticleFilter{ViewerID: "viewer-id"},
+			wantConds: []string{
+				"(a.status = $1 OR a.author_id = $2)",
+				"NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.muter_id = $2 AND m.muted_id = a.author_id)",
+				"NOT EXISTS (SELECT 1 FROM user_blocks b WHERE b.blocker_id = $2 AND b.blocked_id = a.author_id)",
+			},
+			wantArgs: []any{published, "viewer-id"},
+		},
+		{
+			name:   "viewer with tag",
+			filter: model.ArticleFilter{Tag: "go", ViewerID: "viewer-id"},
+			wantConds: []string{
+				"a.taglist @> ARRAY[$1]",
+				"(a.status = $2 OR a.author_id = $3)",
+				"NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.muter_id = $3 AND m.muted_id = a.author_id)",
+				"NOT EXISTS (SELECT 1 FROM user_blocks b WHERE b.blocker_id = $3 AND b.blocked_id = a.author_id)",
+			},
+			wantArgs: []any{"go", published, "viewer-id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conds, args := articleConds(tt.filter)
+			if !reflect.DeepEqual(conds, tt.wantConds) {
+				t.Errorf("conds = %q, want %q", conds, tt.wantConds)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+// CreateArticle and UpdateArticle return the row as stored, so the handlers
+// answer with database times and the allocated slug.
+func TestCreateAndUpdateReturnStoredArticle(t *testing.T) {
+	pool := testPool(t)
+	users := NewPostgresUserStorage(pool, testLog)
+	s := NewPostgresArticleStorage(pool, testLog)
+	author := newTestUser(t, users)
+
+	created, err := s.CreateArticle(model.DBArticle{
+		Slug:     fmt.Sprintf("stored-%d", rand.Int63()),
+		Title:    "Stored",
+		Body:     "body",
+		TagList:  []string{"go"},
+		AuthorID: author,
+		Status:   model.ArticlePublished,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.DeleteArticle(created.Slug) })
+	if created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Errorf("times not returned: created %v, updated %v", created.CreatedAt, created.UpdatedAt)
+	}
+	assertStored(t, s, created)
+
+	created.Title = "Renamed"
+	created.Body = "new body"
+	base := fmt.Sprintf("renamed-%d", rand.Int63())
+	updated, err := s.UpdateArticle(created.Slug, created, model.ArticleEdit{EditorID: author, NewBase: base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.DeleteArticle(updated.Slug) })
+	if updated.Slug != base+"-1" || updated.Title != "Renamed" || updated.Body != "new body" {
+		t.Errorf("updated = %+v", updated)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) || updated.UpdatedAt.Before(created.UpdatedAt) {
+		t.Errorf("times: created %v -> %v, updated %v -> %v", created.CreatedAt, updated.CreatedAt, created.UpdatedAt, updated.UpdatedAt)
+	}
+	assertStored(t, s, updated)
+}
+
+func assertStored(t *testing.T, s *PostgresArticleStorage, returned model.DBArticle) {
+	t.Helper()
+	stored, err := s.GetArticleBySlug(returned.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(returned, stored) {
+		t.Errorf("returned %+v, stored %+v", returned, stored)
+	}
+}
+
+// FeedArticles shows published articles by followed authors only, newest
+// first, and counts all of them when paging.
+func TestFeedArticles(t *testing.T) {
+	pool := testPool(t)
+	users := NewPostgresUserStorage(pool, testLog)
+	s := NewPostgresArticleStorage(pool, testLog)
+	reader, followed, other := newTestUser(t, users), newTestUser(t, users), newTestUser(t, users)
+	if err := users.FollowUser(reader, followed); err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(author string, status string) string {
+		t.Helper()
+		created, err := s.CreateArticle(model.DBArticle{
+			Slug:     fmt.Sprintf("feed-%d", rand.Int63()),
+			Title:    "Feed",
+			Body:     "body",
+			AuthorID: author,
+			Status:   status,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.DeleteArticle(created.Slug) })
+		return created.Slug
+	}
+	older := create(followed, model.ArticlePublished)
+	newer := create(followed, model.ArticlePublished)
+	create(followed, model.ArticleDraft)
+	create(other, model.ArticlePublished)
+	create(reader, model.ArticlePublished)
+
+	page, total, err := s.FeedArticles(reader, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(page) != 1 || page[0].Slug != newer {
+		t.Fatalf("first page = %v of %d, want [%s] of 2", page, total, newer)
+	}
+	page, _, err = s.FeedArticles(reader, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].Slug != older {
+		t.Errorf("second page = %v, want [%s]", page, older)
+	}
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"rwa/internal/model"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditStorage interface {
+	Record(event model.AuditEvent) error
+	List(filter model.AuditFilter) ([]model.AuditEvent, int, error)
+}
+
+// PostgresAuditStorage writes to the append-only audit_log table; the table
+// rejects updates and deletes, so there are no methods for them.
+type PostgresAuditStorage struct {
+	db  *pgxpool.Pool
+	log *slog.Logger
+}
+
+func NewPostgresAuditStorage(db *pgxpool.Pool, log *slog.Logger) *PostgresAuditStorage {
+	return &PostgresAuditStorage{db: db, log: log}
+}
+
+const (
+	opAuditRecord = "repository.PostgresAuditStorage.Record"
+	opAuditList   = "repository.PostgresAuditStorage.List"
+)
+
+func (p PostgresAuditStorage) Record(event model.AuditEvent) error {
+	const op = opAuditRecord
+	ctx := context.Background()
+	if err := recordAudit(ctx, p.db, event); err != nil {
+		p.log.Error("failed to record audit event", "op", op, "action", event.Action, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// withAudit runs action in a transaction that also stores events, so they
+// are kept exactly when the action commits.
+func withAudit(ctx context.Context, db *pgxpool.Pool, action func(tx pgx.Tx) error, events ...model.AuditEvent) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := action(tx); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, events...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// recordAudit stores events through q. Given a transaction, the events
+// commit or roll back together with the action they describe.
+func recordAudit(ctx context.Context, q execer, events ...model.AuditEvent) error {
+	query := `INSERT INTO audit_log (actor_id, action, target_type, target_id, ip, user_agent, details) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	for _, event := range events {
+		details := event.Details
+		if details == nil {
+			details = map[string]any{}
+		}
+		// The column holds addresses only; anything else is dropped rather
+		// than allowed to fail the write.
+		ip := ""
+		if addr, err := netip.ParseAddr(event.IP); err == nil {
+			ip = addr.WithZone("").String()
+		}
+		if _, err := q.Exec(ctx, query, event.ActorID, event.Action, event.TargetType, event.TargetID, ip, event.UserAgent, details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns one page of events matching filter, newest first, and the
+// total number of matches.
+func (p PostgresAuditStorage) List(filter model.AuditFilter) ([]model.AuditEvent, int, error) {
+	const op = opAuditList
+
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "$?", fmt.Sprintf("$%d", len(args))))
+	}
+	if filter.ActorID != "" {
+		add("actor_id::text = $?", filter.ActorID)
+	}
+	if filter.Subject != "" {
+		add("(actor_id::text = $? OR (actor_id IS NULL AND target_id = $?))", filter.Subject)
+	}
+	if filter.Action != "" {
+		add("action = $?", filter.Action)
+	}
+	if filter.TargetID != "" {
+		add("target_id = $?", filter.TargetID)
+	}
+	if filter.IP != "" {
+		add("ip = $?", filter.IP)
+	}
+	if !filter.Since.IsZero() {
+		add("occurred_at >= $?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("occurred_at < $?", filter.Until)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := `SELECT id, occurred_at, actor_id::text, action, target_type, target_id, ip, user_agent, details, count(*) OVER ()
+		FROM audit_log` + where + fmt.Sprintf(` ORDER BY id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	ctx := context.Background()
+	rows, err := p.db.Query(ctx, query, args...)
+	if err != nil {
+		p.log.Error("failed to list audit events", "op", op, "error", err)
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	events := []model.AuditEvent{}
+	total := 0
+	for rows.Next() {
+		var e model.AuditEvent
+		err := rows.Scan(&e.ID, &e.OccurredAt, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &e.IP, &e.UserAgent, &e.Details, &total)
+		if err != nil {
+			p.log.Error("failed to scan audit event", "op", op, "error", err)
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, total, nil
+}
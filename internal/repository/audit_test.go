@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"rwa/internal/model"
+)
+
+// TestAuditedActionCommitsWithItsEvent checks that an action and its audit
+// event are kept or dropped together.
+func TestAuditedActionCommitsWithItsEvent(t *testing.T) {
+	pool := testPool(t)
+	s := NewPostgresUserStorage(pool, testLog)
+	audit := NewPostgresAuditStorage(pool, testLog)
+	uid := newTestUser(t, s)
+
+	// The action column is VARCHAR(64), so this event cannot be stored.
+	bad := model.AuditEvent{Action: strings.Repeat("x", 65), TargetType: model.AuditTargetToken}
+	if _, err := s.CreatePersonalToken(uid, "rejected", "hash-rejected-"+uid, []string{model.ScopeUserRead}, nil, bad); err == nil {
+		t.Fatal("token created although its audit event failed")
+	}
+	tokens, err := s.ListPersonalTokens(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 0 {
+		t.Fatalf("token kept without its audit event: %+v", tokens)
+	}
+
+	good := model.AuditEvent{Action: model.AuditTokenCreated, TargetType: model.AuditTargetToken}
+	token, err := s.CreatePersonalToken(uid, "kept", "hash-kept-"+uid, []string{model.ScopeUserRead}, nil, good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, _, err := audit.List(model.AuditFilter{Action: model.AuditTokenCreated, TargetID: token.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Errorf("got %d audit events for the new token, want 1", len(events))
+	}
+}
@@ -8,4 +8,14 @@ var (
 	ErrEmailAlreadyExists    = errors.New("email already exists")
 	ErrTokenIsNotFound       = errors.New("token is not found")
 	ErrNoResult              = errors.New("no result")
+	ErrResetTokenInvalid     = errors.New("password reset token is invalid or expired")
+	ErrVerificationInvalid   = errors.New("email verification token is invalid or expired")
+	ErrChallengeInvalid      = errors.New("login challenge is invalid or expired")
+	ErrTOTPStepReused        = errors.New("totp code already used")
+	ErrTokenNameExists       = errors.New("token name already exists")
+	ErrArticleNotFound       = errors.New("article not found")
+	ErrSlugUnavailable       = errors.New("could not allocate a unique slug")
+	ErrRevisionNotFound      = errors.New("revision not found")
+	ErrBlocked               = errors.New("blocked")
+	ErrFollowRequestNotFound = errors.New("follow request not found")
 )
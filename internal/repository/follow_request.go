@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"rwa/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// RequestFollow asks to follow a private account. Users who already follow
+// the target stay followers; a block in either direction yields ErrBlocked.
+// The audit events are stored only if a request is filed.
+func (s PostgresUserStorage) RequestFollow(followerID string, targetID string, audit ...model.AuditEvent) (model.FollowState, error) {
+	const op = "PostgresUserStorage.RequestFollow"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return model.FollowNone, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var following, blocked bool
+	err = tx.QueryRow(ctx, `SELECT
+		EXISTS (SELECT 1 FROM subscriptions WHERE sub_id = $1 AND target_user_id = $2),
+		EXISTS (SELECT 1 FROM user_blocks WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1))`,
+		followerID, targetID).Scan(&following, &blocked)
+	if err != nil {
+		s.log.Error("failed to check relationship", slog.String("op", op), slog.String("error", err.Error()))
+		return model.FollowNone, errors.Wrap(err, "failed to check relationship")
+	}
+	if blocked {
+		return model.FollowNone, ErrBlocked
+	}
+	if following {
+		return model.FollowActive, nil
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO follow_requests (requester_id, target_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, followerID, targetID)
+	if err != nil {
+		s.log.Error("failed to create follow request", slog.String("op", op), slog.String("error", err.Error()))
+		return model.FollowNone, errors.Wrap(err, "failed to create follow request")
+	}
+
+	if err := recordAudit(ctx, tx, audit...); err != nil {
+		s.log.Error("failed to record audit event", slog.String("op", op), slog.String("error", err.Error()))
+		return model.FollowNone, errors.Wrap(err, "failed to record audit event")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return model.FollowNone, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.log.Info("follow requested", slog.String("op", op), slog.String("followerID", followerID), slog.String("targetID", targetID))
+	return model.FollowPending, nil
+}
+
+// ListFollowRequests returns the pending requests to follow uid, oldest
+// first.
+func (s PostgresUserStorage) ListFollowRequests(uid string) ([]model.FollowRequest, error) {
+	const op = "PostgresUserStorage.ListFollowRequests"
+
+	query := `SELECT u.id, u.username, u.bio, u.image, u.created_at, u.updated_at, fr.created_at
+		FROM follow_requests fr JOIN users u ON u.id = fr.requester_id
+		WHERE fr.target_id = $1 ORDER BY fr.created_at`
+	ctx := context.Background()
+	rows, err := s.db.Query(ctx, query, uid)
+	if err != nil {
+		s.log.Error("failed to list follow requests", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, errors.Wrap(err, "failed to list follow requests")
+	}
+	defer rows.Close()
+
+	requests := []model.FollowRequest{}
+	for rows.Next() {
+		var fr model.FollowRequest
+		p := &fr.Profile
+		if err := rows.Scan(&p.Id, &p.Username, &p.Bio, &p.Image, &p.CreatedAt, &p.UpdatedAt, &fr.CreatedAt); err != nil {
+			s.log.Error("failed to scan follow request", slog.String("op", op), slog.String("error", err.Error()))
+			return nil, errors.Wrap(err, "failed to scan follow request")
+		}
+		requests = append(requests, fr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read follow requests")
+	}
+	return requests, nil
+}
+
+// ApproveFollowRequest turns a pending request into a subscription and
+// stores the audit events with it.
+func (s PostgresUserStorage) ApproveFollowRequest(targetID string, requesterID string, audit ...model.AuditEvent) error {
+	const op = "PostgresUserStorage.ApproveFollowRequest"
+
+	query := `WITH r AS (DELETE FROM follow_requests WHERE target_id = $1 AND requester_id = $2 RETURNING requester_id, target_id),
+		i AS (INSERT INTO subscriptions (sub_id, target_user_id) SELECT requester_id, target_id FROM r ON CONFLICT DO NOTHING)
+		SELECT count(*) FROM r`
+	ctx := context.Background()
+	err := withAudit(ctx, s.db, func(tx pgx.Tx) error {
+		var approved int
+		if err := tx.QueryRow(ctx, query, targetID, requesterID).Scan(&approved); err != nil {
+			return err
+		}
+		if approved == 0 {
+			return ErrFollowRequestNotFound
+		}
+		return nil
+	}, audit...)
+	if errors.Is(err, ErrFollowRequestNotFound) {
+		return err
+	}
+	if err != nil {
+		s.log.Error("failed to approve follow request", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to approve follow request")
+	}
+
+	s.log.Info("follow request approved", slog.String("op", op), slog.String("targetID", targetID), slog.String("requesterID", requesterID))
+	return nil
+}
+
+// RejectFollowRequest drops a pending request and stores the audit events
+// with it.
+func (s PostgresUserStorage) RejectFollowRequest(targetID string, requesterID string, audit ...model.AuditEvent) error {
+	const op = "PostgresUserStorage.RejectFollowRequest"
+
+	ctx := context.Background()
+	err := withAudit(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `DELETE FROM follow_requests WHERE target_id = $1 AND requester_id = $2`, targetID, requesterID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrFollowRequestNotFound
+		}
+		return nil
+	}, audit...)
+	if errors.Is(err, ErrFollowRequestNotFound) {
+		return err
+	}
+	if err != nil {
+		s.log.Error("failed to reject follow request", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to reject follow request")
+	}
+
+	s.log.Info("follow request rejected", slog.String("op", op), slog.String("targetID", targetID), slog.String("requesterID", requesterID))
+	return nil
+}
+
+// ApproveAllFollowRequests accepts every pending request, for accounts that
+// stop being private.
+func (s PostgresUserStorage) ApproveAllFollowRequests(uid string) error {
+	const op = "PostgresUserStorage.ApproveAllFollowRequests"
+
+	query := `WITH r AS (DELETE FROM follow_requests WHERE target_id = $1 RETURNING requester_id, target_id)
+		INSERT INTO subscriptions (sub_id, target_user_id) SELECT requester_id, target_id FROM r
+		ON CONFLICT DO NOTHING`
+	ctx := context.Background()
+	result, err := s.db.Exec(ctx, query, uid)
+	if err != nil {
+		s.log.Error("failed to approve follow requests", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to approve follow requests")
+	}
+
+	s.log.Info("follow requests approved", slog.String("op", op), slog.String("userID", uid), slog.Int64("count", result.RowsAffected()))
+	return nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"rwa/internal/model"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+// CreateOIDCState stores a pending login. For a re-authentication state
+// reauthUID is the signed-in user being confirmed; it is "" for logins.
+func (s PostgresUserStorage) CreateOIDCState(stateHash string, provider string, nonce string, verifier string, reauthUID string, expiresAt time.Time) error {
+	const op = "PostgresUserStorage.CreateOIDCState"
+
+	query := `INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, reauth_user_id, expires_at) VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6)`
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, query, stateHash, provider, nonce, verifier, reauthUID, expiresAt)
+	if err != nil {
+		s.log.Error("failed to store oidc state", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to store oidc state")
+	}
+	return nil
+}
+
+// ConsumeOIDCState deletes a pending login state and returns it, so every
+// state can complete at most one login.
+func (s PostgresUserStorage) ConsumeOIDCState(stateHash string, provider string) (model.OIDCState, error) {
+	const op = "PostgresUserStorage.ConsumeOIDCState"
+
+	query := `DELETE FROM oidc_login_states WHERE state_hash = $1 AND provider = $2 AND expires_at > now()
+		RETURNING nonce, code_verifier, COALESCE(reauth_user_id::text, ''), created_at`
+	ctx := context.Background()
+	var state model.OIDCState
+	err := s.db.QueryRow(ctx, query, stateHash, provider).Scan(&state.Nonce, &state.Verifier, &state.ReauthUserID, &state.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.OIDCState{}, ErrChallengeInvalid
+		}
+		s.log.Error("failed to consume oidc state", slog.String("op", op), slog.String("error", err.Error()))
+		return model.OIDCState{}, errors.Wrap(err, "failed to consume oidc state")
+	}
+	return state, nil
+}
+
+func (s PostgresUserStorage) CreateReauthToken(uid string, tokenHash string, expiresAt time.Time) error {
+	const op = "PostgresUserStorage.CreateReauthToken"
+
+	query := `INSERT INTO reauth_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, query, tokenHash, uid, expiresAt)
+	if err != nil {
+		s.log.Error("failed to store reauth token", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to store reauth token")
+	}
+	return nil
+}
+
+// ConsumeReauthToken deletes an unexpired reauth token of uid, returning
+// ErrChallengeInvalid if there is none.
+func (s PostgresUserStorage) ConsumeReauthToken(uid string, tokenHash string) error {
+	const op = "PostgresUserStorage.ConsumeReauthToken"
+
+	query := `DELETE FROM reauth_tokens WHERE token_hash = $1 AND user_id = $2 AND expires_at > now()`
+	ctx := context.Background()
+	tag, err := s.db.Exec(ctx, query, tokenHash, uid)
+	if err != nil {
+		s.log.Error("failed to consume reauth token", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to consume reauth token")
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrChallengeInvalid
+	}
+	return nil
+}
+
+func (s PostgresUserStorage) GetUserByIdentity(provider string, subject string) (model.UserTableDB, error) {
+	const op = "PostgresUserStorage.GetUserByIdentity"
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = (SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2)`
+	ctx := context.Background()
+	var userDB model.UserTableDB
+	err := scanUser(s.db.QueryRow(ctx, query, provider, subject), &userDB)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserTableDB{}, ErrUserNotFound
+		}
+		s.log.Error("failed to get user by identity", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserTableDB{}, errors.Wrap(err, "failed to get user by identity")
+	}
+	return userDB, nil
+}
+
+func (s PostgresUserStorage) LinkIdentity(uid string, provider string, subject string, email string) error {
+	const op = "PostgresUserStorage.LinkIdentity"
+
+	query := `INSERT INTO user_identities (provider, subject, user_id, email) VALUES ($1, $2, $3, $4)`
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, query, provider, subject, uid, email)
+	if err != nil {
+		s.log.Error("failed to link identity", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to link identity")
+	}
+
+	s.log.Info("identity linked", slog.String("op", op), slog.String("userID", uid), slog.String("provider", provider))
+	return nil
+}
+
+// CreateUserWithIdentity inserts a user, its external identity and the audit
+// events, which name the new user as actor and target, in one transaction. A
+// taken username surfaces as ErrUsernameAlreadyExists so the caller can retry
+// with another candidate.
+func (s PostgresUserStorage) CreateUserWithIdentity(u model.UserTableDB, provider string, subject string, audit ...model.AuditEvent) (model.UserTableDB, error) {
+	const op = "PostgresUserStorage.CreateUserWithIdentity"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserTableDB{}, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	reserved, err := usernameReserved(ctx, tx, u.Username, "")
+	if err != nil {
+		s.log.Error("failed to check username reservation", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserTableDB{}, errors.Wrap(err, "failed to check username reservation")
+	}
+	if reserved {
+		return model.UserTableDB{}, ErrUsernameAlreadyExists
+	}
+
+	query := `INSERT INTO users (username, email, password_hash, password_salt, bio, image, email_verified) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ` + userColumns
+	var created model.UserTableDB
+	err = scanUser(tx.QueryRow(ctx, query, u.Username, u.Email, u.PasswordHash, u.PasswordSalt, u.Bio, u.Image, u.EmailVerified), &created)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			if pgErr.ConstraintName == "users_username_key" {
+				return model.UserTableDB{}, ErrUsernameAlreadyExists
+			}
+			if pgErr.ConstraintName == "users_email_key" {
+				return model.UserTableDB{}, ErrEmailAlreadyExists
+			}
+		}
+		s.log.Error("failed to create user", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserTableDB{}, errors.Wrap(err, "failed to create user")
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO user_identities (provider, subject, user_id, email) VALUES ($1, $2, $3, $4)`, provider, subject, created.ID, u.Email)
+	if err != nil {
+		s.log.Error("failed to link identity", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserTableDB{}, errors.Wrap(err, "failed to link identity")
+	}
+
+	if err := recordAudit(ctx, tx, byUser(created.ID, audit)...); err != nil {
+		s.log.Error("failed to record audit event", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserTableDB{}, errors.Wrap(err, "failed to record audit event")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return model.UserTableDB{}, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.log.Info("user created from identity", slog.String("op", op), slog.String("userID", created.ID), slog.String("provider", provider))
+	return created, nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"rwa/internal/security"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginAttemptStorage interface {
+	LockedUntil(keys ...string) (time.Time, error)
+	RecordFailure(key string, policy security.ThrottlePolicy) (time.Time, error)
+	Reset(key string) error
+}
+
+type PostgresLoginAttemptStorage struct {
+	db  *pgxpool.Pool
+	log *slog.Logger
+}
+
+func NewPostgresLoginAttemptStorage(db *pgxpool.Pool, log *slog.Logger) *PostgresLoginAttemptStorage {
+	return &PostgresLoginAttemptStorage{db: db, log: log}
+}
+
+const (
+	opLockedUntil   = "repository.PostgresLoginAttemptStorage.LockedUntil"
+	opRecordFailure = "repository.PostgresLoginAttemptStorage.RecordFailure"
+	opReset         = "repository.PostgresLoginAttemptStorage.Reset"
+)
+
+// LockedUntil returns the latest lock expiry among keys, or the zero time if
+// none of them is currently locked.
+func (p PostgresLoginAttemptStorage) LockedUntil(keys ...string) (time.Time, error) {
+	const op = opLockedUntil
+	query := `SELECT COALESCE(MAX(locked_until), 'epoch') FROM login_attempts WHERE key = ANY($1) AND locked_until > now()`
+	ctx := context.Background()
+	var until time.Time
+	err := p.db.QueryRow(ctx, query, keys).Scan(&until)
+	if err != nil {
+		p.log.Error("failed to check lockout", "op", op, "error", err)
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !until.After(time.Now()) {
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// RecordFailure counts a failed attempt for key and applies the policy's
+// delay, returning when the key may try again. Counting and locking happen in
+// one transaction holding the row lock, so concurrent failures are neither
+// lost nor able to shorten each other's lock.
+func (p PostgresLoginAttemptStorage) RecordFailure(key string, policy security.ThrottlePolicy) (time.Time, error) {
+	const op = opRecordFailure
+	query := `INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, now())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < now() - make_interval(secs => $2) THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = now()
+		RETURNING failures`
+	ctx := context.Background()
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		p.log.Error("failed to begin transaction", "op", op, "error", err)
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var failures int
+	err = tx.QueryRow(ctx, query, key, policy.Window.Seconds()).Scan(&failures)
+	if err != nil {
+		p.log.Error("failed to record login failure", "op", op, "key", key, "error", err)
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	delay := policy.Delay(failures)
+	var until time.Time
+	if delay > 0 {
+		err = tx.QueryRow(ctx, `UPDATE login_attempts
+			SET locked_until = GREATEST(locked_until, now() + make_interval(secs => $1))
+			WHERE key = $2 RETURNING locked_until`, delay.Seconds(), key).Scan(&until)
+		if err != nil {
+			p.log.Error("failed to lock key", "op", op, "key", key, "error", err)
+			return time.Time{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		p.log.Error("failed to commit transaction", "op", op, "error", err)
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if delay > 0 {
+		p.log.Warn("login key throttled", "op", op, "key", key, "failures", failures, "until", until)
+	}
+	return until, nil
+}
+
+func (p PostgresLoginAttemptStorage) Reset(key string) error {
+	const op = opReset
+	query := `DELETE FROM login_attempts WHERE key = $1`
+	ctx := context.Background()
+	_, err := p.db.Exec(ctx, query, key)
+	if err != nil {
+		p.log.Error("failed to reset login attempts", "op", op, "key", key, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
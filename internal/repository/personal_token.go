@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"rwa/internal/model"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+// CreatePersonalToken stores a new token and the audit events, which are
+// pointed at the token's id.
+func (s PostgresUserStorage) CreatePersonalToken(uid string, name string, tokenHash string, scopes []string, expiresAt *time.Time, audit ...model.AuditEvent) (model.PersonalAccessToken, error) {
+	const op = "PostgresUserStorage.CreatePersonalToken"
+
+	query := `INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, user_id, name, scopes, created_at, last_used_at, expires_at`
+	ctx := context.Background()
+	var t model.PersonalAccessToken
+	err := withAudit(ctx, s.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, uid, name, tokenHash, scopes, expiresAt).Scan(&t.ID, &t.UID, &t.Name, &t.Scopes, &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		// The events are stored after the action, so they get the id.
+		for i := range audit {
+			audit[i].TargetID = t.ID
+		}
+		return nil
+	}, audit...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			s.log.Warn("token name already used", slog.String("op", op), slog.String("userID", uid))
+			return model.PersonalAccessToken{}, ErrTokenNameExists
+		}
+		s.log.Error("failed to create personal token", slog.String("op", op), slog.String("error", err.Error()))
+		return model.PersonalAccessToken{}, errors.Wrap(err, "failed to create personal token")
+	}
+
+	s.log.Info("personal token created", slog.String("op", op), slog.String("userID", uid), slog.String("tokenID", t.ID))
+	return t, nil
+}
+
+func (s PostgresUserStorage) ListPersonalTokens(uid string) ([]model.PersonalAccessToken, error) {
+	const op = "PostgresUserStorage.ListPersonalTokens"
+
+	query := `SELECT id, user_id, name, scopes, created_at, last_used_at, expires_at FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at`
+	ctx := context.Background()
+	rows, err := s.db.Query(ctx, query, uid)
+	if err != nil {
+		s.log.Error("failed to list personal tokens", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, errors.Wrap(err, "failed to list personal tokens")
+	}
+	defer rows.Close()
+
+	tokens := []model.PersonalAccessToken{}
+	for rows.Next() {
+		var t model.PersonalAccessToken
+		if err := rows.Scan(&t.ID, &t.UID, &t.Name, &t.Scopes, &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt); err != nil {
+			s.log.Error("failed to scan personal token", slog.String("op", op), slog.String("error", err.Error()))
+			return nil, errors.Wrap(err, "failed to scan personal token")
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read personal tokens")
+	}
+	return tokens, nil
+}
+
+// DeletePersonalToken revokes one of uid's tokens and stores the audit
+// events with the revocation.
+func (s PostgresUserStorage) DeletePersonalToken(uid string, id string, audit ...model.AuditEvent) error {
+	const op = "PostgresUserStorage.DeletePersonalToken"
+
+	query := `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`
+	ctx := context.Background()
+	err := withAudit(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, id, uid)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrTokenIsNotFound
+		}
+		return nil
+	}, audit...)
+	if errors.Is(err, ErrTokenIsNotFound) {
+		return err
+	}
+	if err != nil {
+		s.log.Error("failed to delete personal token", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to delete personal token")
+	}
+
+	s.log.Info("personal token revoked", slog.String("op", op), slog.String("userID", uid), slog.String("tokenID", id))
+	return nil
+}
+
+// UsePersonalToken resolves a presented token and stamps its last use in the
+// same statement.
+func (s PostgresUserStorage) UsePersonalToken(tokenHash string) (model.PersonalAccessToken, error) {
+	const op = "PostgresUserStorage.UsePersonalToken"
+
+	query := `UPDATE personal_access_tokens SET last_used_at = now()
+		WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > now())
+		RETURNING id, user_id, name, scopes, created_at, last_used_at, expires_at`
+	ctx := context.Background()
+	var t model.PersonalAccessToken
+	err := s.db.QueryRow(ctx, query, tokenHash).Scan(&t.ID, &t.UID, &t.Name, &t.Scopes, &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.PersonalAccessToken{}, ErrTokenIsNotFound
+		}
+		s.log.Error("failed to use personal token", slog.String("op", op), slog.String("error", err.Error()))
+		return model.PersonalAccessToken{}, errors.Wrap(err, "failed to use personal token")
+	}
+	return t, nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"rwa/internal/model"
+
+	"github.com/pkg/errors"
+)
+
+// BlockUser records a block and drops subscriptions and follow requests in
+// both directions.
+func (s PostgresUserStorage) BlockUser(blockerID string, blockedID string) error {
+	const op = "PostgresUserStorage.BlockUser"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, blockerID, blockedID)
+	if err != nil {
+		s.log.Error("failed to block user", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to block user")
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM subscriptions WHERE (sub_id = $1 AND target_user_id = $2) OR (sub_id = $2 AND target_user_id = $1)`, blockerID, blockedID)
+	if err != nil {
+		s.log.Error("failed to remove subscriptions", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to remove subscriptions")
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM follow_requests WHERE (requester_id = $1 AND target_id = $2) OR (requester_id = $2 AND target_id = $1)`, blockerID, blockedID)
+	if err != nil {
+		s.log.Error("failed to remove follow requests", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to remove follow requests")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.log.Info("user blocked", slog.String("op", op), slog.String("blockerID", blockerID), slog.String("blockedID", blockedID))
+	return nil
+}
+
+func (s PostgresUserStorage) UnblockUser(blockerID string, blockedID string) error {
+	const op = "PostgresUserStorage.UnblockUser"
+
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, `DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`, blockerID, blockedID)
+	if err != nil {
+		s.log.Error("failed to unblock user", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to unblock user")
+	}
+
+	s.log.Info("user unblocked", slog.String("op", op), slog.String("blockerID", blockerID), slog.String("blockedID", blockedID))
+	return nil
+}
+
+func (s PostgresUserStorage) MuteUser(muterID string, mutedID string) error {
+	const op = "PostgresUserStorage.MuteUser"
+
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, `INSERT INTO user_mutes (muter_id, muted_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, muterID, mutedID)
+	if err != nil {
+		s.log.Error("failed to mute user", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to mute user")
+	}
+
+	s.log.Info("user muted", slog.String("op", op), slog.String("muterID", muterID), slog.String("mutedID", mutedID))
+	return nil
+}
+
+func (s PostgresUserStorage) UnmuteUser(muterID string, mutedID string) error {
+	const op = "PostgresUserStorage.UnmuteUser"
+
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, `DELETE FROM user_mutes WHERE muter_id = $1 AND muted_id = $2`, muterID, mutedID)
+	if err != nil {
+		s.log.Error("failed to unmute user", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to unmute user")
+	}
+
+	s.log.Info("user unmuted", slog.String("op", op), slog.String("muterID", muterID), slog.String("mutedID", mutedID))
+	return nil
+}
+
+// ListBlocked returns the profiles uid has blocked, most recent first.
+func (s PostgresUserStorage) ListBlocked(uid string) ([]model.Profile, error) {
+	return s.listRelated("PostgresUserStorage.ListBlocked", `SELECT u.id, u.username, u.bio, u.image, u.created_at, u.updated_at
+		FROM user_blocks b JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1 ORDER BY b.created_at DESC`, uid, func(p *model.Profile) { p.Blocking = true })
+}
+
+// ListMuted returns the profiles uid has muted, most recent first.
+func (s PostgresUserStorage) ListMuted(uid string) ([]model.Profile, error) {
+	return s.listRelated("PostgresUserStorage.ListMuted", `SELECT u.id, u.username, u.bio, u.image, u.created_at, u.updated_at
+		FROM user_mutes m JOIN users u ON u.id = m.muted_id
+		WHERE m.muter_id = $1 ORDER BY m.created_at DESC`, uid, func(p *model.Profile) { p.Muting = true })
+}
+
+func (s PostgresUserStorage) listRelated(op string, query string, uid string, mark func(*model.Profile)) ([]model.Profile, error) {
+	ctx := context.Background()
+	rows, err := s.db.Query(ctx, query, uid)
+	if err != nil {
+		s.log.Error("failed to list profiles", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, errors.Wrap(err, "failed to list profiles")
+	}
+	defer rows.Close()
+
+	profiles := []model.Profile{}
+	for rows.Next() {
+		var p model.Profile
+		if err := rows.Scan(&p.Id, &p.Username, &p.Bio, &p.Image, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			s.log.Error("failed to scan profile", slog.String("op", op), slog.String("error", err.Error()))
+			return nil, errors.Wrap(err, "failed to scan profile")
+		}
+		mark(&p)
+		profiles = append(profiles, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read profiles")
+	}
+	return profiles, nil
+}
+
+// GetRelationship reports in one query how viewerID relates to targetID.
+func (s PostgresUserStorage) GetRelationship(viewerID string, targetID string) (model.Relationship, error) {
+	const op = "PostgresUserStorage.GetRelationship"
+
+	query := `SELECT
+		EXISTS (SELECT 1 FROM subscriptions WHERE sub_id = $1 AND target_user_id = $2),
+		EXISTS (SELECT 1 FROM follow_requests WHERE requester_id = $1 AND target_id = $2),
+		EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2),
+		EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = $2 AND blocked_id = $1),
+		EXISTS (SELECT 1 FROM user_mutes WHERE muter_id = $1 AND muted_id = $2)`
+	ctx := context.Background()
+	var rel model.Relationship
+	err := s.db.QueryRow(ctx, query, viewerID, targetID).Scan(&rel.Following, &rel.Requested, &rel.Blocking, &rel.BlockedBy, &rel.Muting)
+	if err != nil {
+		s.log.Error("failed to get relationship", slog.String("op", op), slog.String("error", err.Error()))
+		return model.Relationship{}, errors.Wrap(err, "failed to get relationship")
+	}
+	return rel, nil
+}
+
+// GetFollowStates reports in one query viewerID's follow state towards each
+// of targetIDs. Targets the viewer neither follows nor asked to follow are
+// left out.
+func (s PostgresUserStorage) GetFollowStates(viewerID string, targetIDs []string) (map[string]model.FollowState, error) {
+	const op = "PostgresUserStorage.GetFollowStates"
+
+	// The ids go over as text; the cast keeps the comparisons on uuid.
+	query := `SELECT target_user_id, TRUE FROM subscriptions WHERE sub_id = $1 AND target_user_id = ANY($2::text[]::uuid[])
+		UNION ALL
+		SELECT target_id, FALSE FROM follow_requests WHERE requester_id = $1 AND target_id = ANY($2::text[]::uuid[])`
+	ctx := context.Background()
+	rows, err := s.db.Query(ctx, query, viewerID, targetIDs)
+	if err != nil {
+		s.log.Error("failed to get follow states", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, errors.Wrap(err, "failed to get follow states")
+	}
+	defer rows.Close()
+
+	states := make(map[string]model.FollowState)
+	for rows.Next() {
+		var id string
+		var following bool
+		if err := rows.Scan(&id, &following); err != nil {
+			s.log.Error("failed to scan follow state", slog.String("op", op), slog.String("error", err.Error()))
+			return nil, errors.Wrap(err, "failed to scan follow state")
+		}
+		if following {
+			states[id] = model.FollowActive
+		} else if states[id] != model.FollowActive {
+			states[id] = model.FollowPending
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read follow states")
+	}
+	return states, nil
+}
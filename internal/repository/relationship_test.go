@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"rwa/internal/model"
+)
+
+func TestBlockUser(t *testing.T) {
+	s := NewPostgresUserStorage(testPool(t), testLog)
+	alice, bob := newTestUser(t, s), newTestUser(t, s)
+
+	if err := s.FollowUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.FollowUser(bob, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BlockUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	// Blocking twice is not an error.
+	if err := s.BlockUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	rel, err := s.GetRelationship(alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (model.Relationship{Blocking: true}); rel != want {
+		t.Errorf("alice -> bob = %+v, want %+v", rel, want)
+	}
+	rel, err = s.GetRelationship(bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (model.Relationship{BlockedBy: true}); rel != want {
+		t.Errorf("bob -> alice = %+v, want %+v", rel, want)
+	}
+
+	blocked, err := s.ListBlocked(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocked) != 1 || blocked[0].Id != bob || !blocked[0].Blocking {
+		t.Errorf("ListBlocked = %+v", blocked)
+	}
+
+	if err := s.UnblockUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	rel, err = s.GetRelationship(alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The follows removed by the block stay removed.
+	if rel != (model.Relationship{}) {
+		t.Errorf("after unblock alice -> bob = %+v", rel)
+	}
+}
+
+func TestBlockUserDropsFollowRequests(t *testing.T) {
+	s := NewPostgresUserStorage(testPool(t), testLog)
+	alice, bob := newTestUser(t, s), newTestUser(t, s)
+
+	state, err := s.RequestFollow(bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != model.FollowPending {
+		t.Fatalf("RequestFollow = %v, want pending", state)
+	}
+	if err := s.BlockUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	requests, err := s.ListFollowRequests(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("follow requests after block = %+v", requests)
+	}
+	if _, err := s.RequestFollow(bob, alice); err != ErrBlocked {
+		t.Errorf("RequestFollow while blocked = %v, want ErrBlocked", err)
+	}
+}
+
+func TestMuteUser(t *testing.T) {
+	s := NewPostgresUserStorage(testPool(t), testLog)
+	alice, bob := newTestUser(t, s), newTestUser(t, s)
+
+	if err := s.FollowUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MuteUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MuteUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	// Muting leaves follows alone and is invisible to the muted user.
+	rel, err := s.GetRelationship(alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (model.Relationship{Following: true, Muting: true}); rel != want {
+		t.Errorf("alice -> bob = %+v, want %+v", rel, want)
+	}
+	rel, err = s.GetRelationship(bob, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel != (model.Relationship{}) {
+		t.Errorf("bob -> alice = %+v", rel)
+	}
+
+	muted, err := s.ListMuted(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(muted) != 1 || muted[0].Id != bob || !muted[0].Muting {
+		t.Errorf("ListMuted = %+v", muted)
+	}
+
+	if err := s.UnmuteUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	muted, err = s.ListMuted(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(muted) != 0 {
+		t.Errorf("ListMuted after unmute = %+v", muted)
+	}
+}
+
+func TestGetFollowStates(t *testing.T) {
+	s := NewPostgresUserStorage(testPool(t), testLog)
+	alice, bob, carol, dave := newTestUser(t, s), newTestUser(t, s), newTestUser(t, s), newTestUser(t, s)
+
+	if err := s.FollowUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RequestFollow(alice, carol); err != nil {
+		t.Fatal(err)
+	}
+	// Someone else's follow does not leak into alice's states.
+	if err := s.FollowUser(dave, carol); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err := s.GetFollowStates(alice, []string{bob, carol, dave})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]model.FollowState{bob: model.FollowActive, carol: model.FollowPending}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("states = %v, want %v", states, want)
+	}
+
+	// Authors repeat across a page of articles, and the viewer may be one.
+	states, err = s.GetFollowStates(alice, []string{bob, carol, bob, alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("repeated targets: states = %v, want %v", states, want)
+	}
+
+	states, err = s.GetFollowStates(alice, nil)
+	if err != nil || len(states) != 0 {
+		t.Errorf("no targets: states %v, err %v", states, err)
+	}
+}
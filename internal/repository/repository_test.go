@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"rwa/internal/model"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to the migrated database in DB_URL, skipping the test
+// when it is not set.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("DB_URL")
+	if dsn == "" {
+		t.Skip("DB_URL is not set")
+	}
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+var testLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newTestUser registers a user with a random name and returns its id. The
+// user and everything it owns are deleted when the test ends.
+func newTestUser(t *testing.T, s *PostgresUserStorage) string {
+	t.Helper()
+	name := fmt.Sprintf("test_%d", rand.Int63())
+	if err := s.RegisterUser(name, name+"@example.com", "correct horse battery"); err != nil {
+		t.Fatal(err)
+	}
+	u, err := s.GetUserForAuth("", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.DeleteAccount(u.ID, model.ArticlesDelete, time.Now()); err != nil {
+			t.Error(err)
+		}
+	})
+	return u.ID
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rwa/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	opListRevisions     = "repository.PostgresArticleStorage.ListRevisions"
+	opGetRevision       = "repository.PostgresArticleStorage.GetRevision"
+	opRenderedBodies    = "repository.PostgresArticleStorage.RenderedBodies"
+	opStoreRenderedBody = "repository.PostgresArticleStorage.StoreRenderedBody"
+)
+
+// ListRevisions returns the revisions of the article at slug, newest first,
+// without their bodies.
+func (p PostgresArticleStorage) ListRevisions(slug string) ([]model.Revision, error) {
+	const op = opListRevisions
+	query := `
+		SELECT r.number, r.title, r.description, r.taglist, COALESCE(u.username, ''), r.restored_from, r.created_at
+		FROM article_revisions r
+		LEFT JOIN users u ON u.id = r.editor_id
+		WHERE r.article_slug = $1
+		ORDER BY r.number DESC`
+	ctx := context.Background()
+	rows, err := p.db.Query(ctx, query, slug)
+	if err != nil {
+		p.log.Error("failed to list revisions", "op", op, "slug", slug, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	revisions := []model.Revision{}
+	for rows.Next() {
+		var rev model.Revision
+		err = rows.Scan(&rev.Number, &rev.Title, &rev.Description, &rev.TagList, &rev.Editor, &rev.RestoredFrom, &rev.CreatedAt)
+		if err != nil {
+			p.log.Error("failed to scan revision", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return revisions, nil
+}
+
+// GetRevision returns revision number of the article at slug, or
+// ErrRevisionNotFound.
+func (p PostgresArticleStorage) GetRevision(slug string, number int) (model.Revision, error) {
+	const op = opGetRevision
+	query := `
+		SELECT r.number, r.title, r.description, r.body, r.taglist, COALESCE(u.username, ''), r.restored_from, r.created_at
+		FROM article_revisions r
+		LEFT JOIN users u ON u.id = r.editor_id
+		WHERE r.article_slug = $1 AND r.number = $2`
+	ctx := context.Background()
+	var rev model.Revision
+	err := p.db.QueryRow(ctx, query, slug, number).Scan(&rev.Number, &rev.Title, &rev.Description, &rev.Body, &rev.TagList, &rev.Editor, &rev.RestoredFrom, &rev.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Revision{}, ErrRevisionNotFound
+		}
+		p.log.Error("failed to get revision", "op", op, "slug", slug, "number", number, "error", err)
+		return model.Revision{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return rev, nil
+}
+
+// RenderedBodies returns the latest revision of each article in slugs with
+// its cached HTML, keyed by slug.
+func (p PostgresArticleStorage) RenderedBodies(slugs []string) (map[string]model.RenderedBody, error) {
+	const op = opRenderedBodies
+	query := `
+		SELECT DISTINCT ON (article_slug) article_slug, number, body, COALESCE(body_html, ''), COALESCE(renderer_version, 0)
+		FROM article_revisions
+		WHERE article_slug = ANY($1)
+		ORDER BY article_slug, number DESC`
+	ctx := context.Background()
+	rows, err := p.db.Query(ctx, query, slugs)
+	if err != nil {
+		p.log.Error("failed to get rendered bodies", "op", op, "error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	bodies := make(map[string]model.RenderedBody, len(slugs))
+	for rows.Next() {
+		var slug string
+		var body model.RenderedBody
+		err = rows.Scan(&slug, &body.Revision, &body.Body, &body.HTML, &body.Version)
+		if err != nil {
+			p.log.Error("failed to scan rendered body", "op", op, "error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		bodies[slug] = body
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return bodies, nil
+}
+
+// StoreRenderedBody caches html, produced by renderer version, for revision
+// number of the article at slug.
+func (p PostgresArticleStorage) StoreRenderedBody(slug string, number int, html string, version int) error {
+	const op = opStoreRenderedBody
+	query := `UPDATE article_revisions SET body_html = $3, renderer_version = $4 WHERE article_slug = $1 AND number = $2`
+	ctx := context.Background()
+	_, err := p.db.Exec(ctx, query, slug, number, html, version)
+	if err != nil {
+		p.log.Error("failed to store rendered body", "op", op, "slug", slug, "number", number, "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
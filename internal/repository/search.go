@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"rwa/internal/model"
+	"strings"
+	"unicode"
+)
+
+// Snippet match markers. ts_headline inserts them around matched words; as
+// control characters they pass HTML escaping unchanged and are then turned
+// into <mark> tags. Control characters are stripped from the text fed to
+// ts_headline so an article cannot forge matches.
+const (
+	snippetStart = "\x02"
+	snippetStop  = "\x03"
+)
+
+const snippetOptions = `StartSel=` + snippetStart + `, StopSel=` + snippetStop + `, MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=" … "`
+
+// SearchArticles runs a full-text search for text over the articles matching
+// filter, best matches first, and returns one page of hits with the total
+// number of hits. text takes web search syntax: quoted phrases, "or" and
+// -excluded words.
+func (p PostgresArticleStorage) SearchArticles(text string, filter model.ArticleFilter, limit, offset int) ([]model.ArticleSearchResult, int, error) {
+	const op = opSearchArticles
+
+	conds, args := articleConds(filter)
+	args = append(args, text)
+	n := len(args)
+	// The query is parsed by both stemmers, like the indexed vector. The
+	// snippet can only be parsed with one, chosen by the query's script.
+	args = append(args, headlineConfig(text), snippetOptions, limit, offset)
+	// The page is picked in the subquery, so ts_headline, which reparses
+	// the whole body, runs only for the hits returned rather than every
+	// match.
+	query := fmt.Sprintf(`
+		SELECT `+articleColumns+`, `+authorColumns+`, p.rank,
+			ts_headline($%[2]d::text::regconfig, regexp_replace(a.description || E'\n' || a.body, '[\x01-\x08\x0b\x0c\x0e-\x1f\x7f]', '', 'g'), p.query, $%[3]d),
+			p.total`+articleFrom+`
+		JOIN (
+			SELECT a.slug, a.created_at, q.query, ts_rank_cd(a.search_vector, q.query) AS rank, count(*) OVER () AS total`+articleFrom+`
+			CROSS JOIN (SELECT websearch_to_tsquery('english', $%[1]d) || websearch_to_tsquery('russian', $%[1]d) AS query) q
+			WHERE a.search_vector @@ q.query AND %[6]s
+			ORDER BY rank DESC, a.created_at DESC
+			LIMIT $%[4]d OFFSET $%[5]d
+		) p ON p.slug = a.slug
+		ORDER BY p.rank DESC, p.created_at DESC`, n, n+1, n+2, n+3, n+4, strings.Join(conds, " AND "))
+
+	ctx := context.Background()
+	rows, err := p.db.Query(ctx, query, args...)
+	if err != nil {
+		p.log.Error("failed to search articles", "op", op, "query", text, "error", err)
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	results := []model.ArticleSearchResult{}
+	total := 0
+	for rows.Next() {
+		var hit model.ArticleSearchResult
+		a := &hit.DBArticleResponseWithAuthorUsername
+		err = rows.Scan(&a.Slug, &a.Title, &a.Description, &a.Body, &a.TagList, &a.CreatedAt, &a.UpdatedAt, &a.FavoritesCount, &a.Status, &a.PublishAt, &a.Author.Username, &a.Author.Bio, &a.Author.Image, &a.Author.ID, &hit.Rank, &hit.Snippet, &total)
+		if err != nil {
+			p.log.Error("failed to scan search hit", "op", op, "error", err)
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		hit.Snippet = highlightSnippet(hit.Snippet)
+		results = append(results, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return results, total, nil
+}
+
+// headlineConfig picks the text search configuration ts_headline parses the
+// snippet with: Russian if the query has any Cyrillic, English otherwise.
+func headlineConfig(text string) string {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return "russian"
+		}
+	}
+	return "english"
+}
+
+// highlightSnippet escapes a ts_headline result for HTML and replaces the
+// match markers with <mark> tags. Stray markers are dropped so the tags
+// always pair up.
+func highlightSnippet(s string) string {
+	var b strings.Builder
+	open := false
+	for s != "" {
+		i := strings.IndexAny(s, snippetStart+snippetStop)
+		if i < 0 {
+			b.WriteString(html.EscapeString(s))
+			break
+		}
+		b.WriteString(html.EscapeString(s[:i]))
+		switch s[i : i+1] {
+		case snippetStart:
+			if !open {
+				b.WriteString("<mark>")
+				open = true
+			}
+		case snippetStop:
+			if open {
+				b.WriteString("</mark>")
+				open = false
+			}
+		}
+		s = s[i+1:]
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"rwa/internal/model"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "no matches here", "no matches here"},
+		{"match", "a \x02go\x03 gopher", "a <mark>go</mark> gopher"},
+		{"two matches", "\x02go\x03 and \x02rust\x03", "<mark>go</mark> and <mark>rust</mark>"},
+		{"escaped", "<b>\x02x\x03</b> & \"y\"", "&lt;b&gt;<mark>x</mark>&lt;/b&gt; &amp; &#34;y&#34;"},
+		{"escaped inside match", "\x02<i>\x03", "<mark>&lt;i&gt;</mark>"},
+		{"stray stop", "a\x03b", "ab"},
+		{"nested start", "\x02a\x02b\x03c", "<mark>ab</mark>c"},
+		{"unclosed", "a\x02b", "a<mark>b</mark>"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightSnippet(tt.in); got != tt.want {
+				t.Errorf("highlightSnippet(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeadlineConfig(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"golang generics", "english"},
+		{"", "english"},
+		{"горутины", "russian"},
+		{"go горутины", "russian"},
+		{"\"exact phrase\" -excluded", "english"},
+		{"café über", "english"},
+	}
+	for _, tt := range tests {
+		if got := headlineConfig(tt.query); got != tt.want {
+			t.Errorf("headlineConfig(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// TestSearchArticlesPage checks that paging in a subquery still reports the
+// total, keeps the ranking order and highlights every returned hit.
+func TestSearchArticlesPage(t *testing.T) {
+	pool := testPool(t)
+	users := NewPostgresUserStorage(pool, testLog)
+	s := NewPostgresArticleStorage(pool, testLog)
+	author := newTestUser(t, users)
+
+	// A made-up word of letters only, so no other article matches and the
+	// stemmer leaves it alone.
+	letters := make([]byte, 12)
+	for i := range letters {
+		letters[i] = byte('a' + rand.Intn(26))
+	}
+	word := "zq" + string(letters)
+	for i, body := range []string{word, word + " " + word, word + " " + word + " " + word} {
+		created, err := s.CreateArticle(model.DBArticle{
+			Slug:     fmt.Sprintf("search-%d-%d", i, rand.Int63()),
+			Title:    "Search",
+			Body:     body,
+			AuthorID: author,
+			Status:   model.ArticlePublished,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.DeleteArticle(created.Slug) })
+	}
+
+	hits, total, err := s.SearchArticles(word, model.ArticleFilter{}, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(hits) != 2 {
+		t.Fatalf("got %d hits of %d, want 2 of 3", len(hits), total)
+	}
+	if hits[0].Rank < hits[1].Rank {
+		t.Errorf("hits out of order: %v before %v", hits[0].Rank, hits[1].Rank)
+	}
+	for _, hit := range hits {
+		if !strings.Contains(hit.Snippet, "<mark>"+word+"</mark>") {
+			t.Errorf("snippet %q does not highlight the match", hit.Snippet)
+		}
+	}
+}
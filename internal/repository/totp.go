@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// maxChallengeAttempts bounds how many wrong second-factor codes a single
+// login challenge tolerates before it is discarded.
+const maxChallengeAttempts = 5
+
+func (s PostgresUserStorage) SetPendingTOTPSecret(uid string, sealedSecret string) error {
+	const op = "PostgresUserStorage.SetPendingTOTPSecret"
+
+	query := `UPDATE users SET totp_secret = $1, updated_at = now() WHERE id = $2 AND totp_enabled = FALSE`
+	ctx := context.Background()
+	result, err := s.db.Exec(ctx, query, sealedSecret, uid)
+	if err != nil {
+		s.log.Error("failed to store totp secret", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to store totp secret")
+	}
+	if result.RowsAffected() == 0 {
+		s.log.Warn("totp already enabled or user missing", slog.String("op", op), slog.String("userID", uid))
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// EnableTOTP switches two-factor on and replaces the user's recovery codes in
+// one transaction.
+func (s PostgresUserStorage) EnableTOTP(uid string, step int64, recoveryCodeHashes []string) error {
+	const op = "PostgresUserStorage.EnableTOTP"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `UPDATE users SET totp_enabled = TRUE, totp_last_step = $1, updated_at = now() WHERE id = $2`, step, uid)
+	if err != nil {
+		s.log.Error("failed to enable totp", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to enable totp")
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, uid)
+	if err != nil {
+		s.log.Error("failed to clear recovery codes", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to clear recovery codes")
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.Exec(ctx, `INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, uid, hash)
+		if err != nil {
+			s.log.Error("failed to store recovery code", slog.String("op", op), slog.String("error", err.Error()))
+			return errors.Wrap(err, "failed to store recovery code")
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.log.Info("totp enabled", slog.String("op", op), slog.String("userID", uid))
+	return nil
+}
+
+func (s PostgresUserStorage) DisableTOTP(uid string) error {
+	const op = "PostgresUserStorage.DisableTOTP"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `UPDATE users SET totp_enabled = FALSE, totp_secret = '', totp_last_step = 0, updated_at = now() WHERE id = $1`, uid)
+	if err != nil {
+		s.log.Error("failed to disable totp", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to disable totp")
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, uid)
+	if err != nil {
+		s.log.Error("failed to clear recovery codes", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to clear recovery codes")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.log.Info("totp disabled", slog.String("op", op), slog.String("userID", uid))
+	return nil
+}
+
+// MarkTOTPStep records step as the last accepted one. It fails with
+// ErrTOTPStepReused if that step (or a later one) was already accepted, which
+// stops a code from being replayed within its validity window.
+func (s PostgresUserStorage) MarkTOTPStep(uid string, step int64) error {
+	const op = "PostgresUserStorage.MarkTOTPStep"
+
+	query := `UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`
+	ctx := context.Background()
+	result, err := s.db.Exec(ctx, query, step, uid)
+	if err != nil {
+		s.log.Error("failed to record totp step", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to record totp step")
+	}
+	if result.RowsAffected() == 0 {
+		s.log.Warn("totp step reused", slog.String("op", op), slog.String("userID", uid))
+		return ErrTOTPStepReused
+	}
+	return nil
+}
+
+func (s PostgresUserStorage) CreateLoginChallenge(uid string, tokenHash string, expiresAt time.Time) error {
+	const op = "PostgresUserStorage.CreateLoginChallenge"
+
+	query := `INSERT INTO login_challenges (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, query, tokenHash, uid, expiresAt)
+	if err != nil {
+		s.log.Error("failed to create login challenge", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to create login challenge")
+	}
+	return nil
+}
+
+// ClaimLoginChallenge counts an attempt against a pending challenge and
+// returns the user it belongs to. The attempt is counted before the code is
+// checked, in the same statement as the limit, so parallel guesses cannot
+// exceed maxChallengeAttempts.
+func (s PostgresUserStorage) ClaimLoginChallenge(tokenHash string) (string, error) {
+	const op = "PostgresUserStorage.ClaimLoginChallenge"
+
+	query := `UPDATE login_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2
+		RETURNING user_id`
+	ctx := context.Background()
+	var uid string
+	err := s.db.QueryRow(ctx, query, tokenHash, maxChallengeAttempts).Scan(&uid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrChallengeInvalid
+		}
+		s.log.Error("failed to claim login challenge", slog.String("op", op), slog.String("error", err.Error()))
+		return "", errors.Wrap(err, "failed to claim login challenge")
+	}
+	return uid, nil
+}
+
+// ConsumeLoginChallenge closes a claimed challenge; only the first caller
+// succeeds.
+func (s PostgresUserStorage) ConsumeLoginChallenge(tokenHash string) error {
+	const op = "PostgresUserStorage.ConsumeLoginChallenge"
+
+	ctx := context.Background()
+	consumed, err := consumeLoginChallenge(ctx, s.db, tokenHash)
+	if err != nil {
+		s.log.Error("failed to consume login challenge", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to consume login challenge")
+	}
+	if !consumed {
+		return ErrChallengeInvalid
+	}
+	return nil
+}
+
+// ConsumeLoginChallengeWithRecoveryCode closes a claimed challenge and burns
+// one of uid's recovery codes in one transaction, reporting whether the code
+// was valid. The challenge is closed first and nothing is kept unless both
+// succeed, so a recovery code is never spent on a challenge that is gone.
+func (s PostgresUserStorage) ConsumeLoginChallengeWithRecoveryCode(tokenHash string, uid string, codeHash string) (bool, error) {
+	const op = "PostgresUserStorage.ConsumeLoginChallengeWithRecoveryCode"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return false, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	consumed, err := consumeLoginChallenge(ctx, tx, tokenHash)
+	if err != nil {
+		s.log.Error("failed to consume login challenge", slog.String("op", op), slog.String("error", err.Error()))
+		return false, errors.Wrap(err, "failed to consume login challenge")
+	}
+	if !consumed {
+		return false, ErrChallengeInvalid
+	}
+
+	result, err := tx.Exec(ctx, `UPDATE totp_recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, uid, codeHash)
+	if err != nil {
+		s.log.Error("failed to use recovery code", slog.String("op", op), slog.String("error", err.Error()))
+		return false, errors.Wrap(err, "failed to use recovery code")
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return false, errors.Wrap(err, "failed to commit transaction")
+	}
+	s.log.Info("recovery code used", slog.String("op", op), slog.String("userID", uid))
+	return true, nil
+}
+
+// consumeLoginChallenge marks a challenge used unless it already is, has
+// expired or ran out of attempts. The claim of the current attempt is
+// already counted, hence <=.
+func consumeLoginChallenge(ctx context.Context, q execer, tokenHash string) (bool, error) {
+	query := `UPDATE login_challenges SET used_at = now() WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts <= $2`
+	result, err := q.Exec(ctx, query, tokenHash, maxChallengeAttempts)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
@@ -15,19 +15,73 @@ import (
 )
 
 type UserStorage interface {
-	AddUser(u model.UserTableDB) error
+	AddUser(u model.UserTableDB, audit ...model.AuditEvent) error
 	GetUserForApi(id string) (model.User, error)
 	GetUserForAuth(email string, username string) (model.UserTableDB, error)
 	GetUserForUpdate(id string) (model.UserTableDB, error)
-	DeleteUser(email string, username string) error
+	DeleteAccount(uid string, articles string, reservedUntil time.Time) error
+	UpdateProfile(u model.UserTableDB, reservedUntil time.Time, audit ...model.AuditEvent) error
+	ResolveFormerUsername(username string) (string, error)
+	ExportUser(uid string) (model.UserExport, error)
 	UpdateUser(u model.UserTableDB) error
+	UpdatePassword(uid string, hash string, salt string) error
 	AddToken(token string, uid string) error
 	GetToken(token string) (model.UserAuthToken, error)
 	DeleteToken(token string) error
 	FindTokenByUID(uid string) (model.UserAuthToken, error)
-	FollowUser(followerId string, followedId string) error
+	RevokeToken(jti string, uid string, expiresAt time.Time) error
+	GetRevokedTokens() (map[string]time.Time, error)
+	GetTokensByUID(uid string) ([]model.UserAuthToken, error)
+	CreatePasswordReset(uid string, tokenHash string, expiresAt time.Time) error
+	FindPasswordReset(tokenHash string) (string, error)
+	ConsumePasswordReset(tokenHash string) (string, error)
+	CreateEmailVerification(uid string, email string, tokenHash string, expiresAt time.Time) error
+	ConsumeEmailVerification(tokenHash string, audit ...model.AuditEvent) (string, error)
+	SetPendingTOTPSecret(uid string, sealedSecret string) error
+	EnableTOTP(uid string, step int64, recoveryCodeHashes []string) error
+	DisableTOTP(uid string) error
+	MarkTOTPStep(uid string, step int64) error
+	CreateLoginChallenge(uid string, tokenHash string, expiresAt time.Time) error
+	ClaimLoginChallenge(tokenHash string) (string, error)
+	ConsumeLoginChallenge(tokenHash string) error
+	ConsumeLoginChallengeWithRecoveryCode(tokenHash string, uid string, codeHash string) (bool, error)
+	CreatePersonalToken(uid string, name string, tokenHash string, scopes []string, expiresAt *time.Time, audit ...model.AuditEvent) (model.PersonalAccessToken, error)
+	ListPersonalTokens(uid string) ([]model.PersonalAccessToken, error)
+	DeletePersonalToken(uid string, id string, audit ...model.AuditEvent) error
+	UsePersonalToken(tokenHash string) (model.PersonalAccessToken, error)
+	CreateOIDCState(stateHash string, provider string, nonce string, verifier string, reauthUID string, expiresAt time.Time) error
+	ConsumeOIDCState(stateHash string, provider string) (model.OIDCState, error)
+	CreateReauthToken(uid string, tokenHash string, expiresAt time.Time) error
+	ConsumeReauthToken(uid string, tokenHash string) error
+	GetUserByIdentity(provider string, subject string) (model.UserTableDB, error)
+	LinkIdentity(uid string, provider string, subject string, email string) error
+	CreateUserWithIdentity(u model.UserTableDB, provider string, subject string, audit ...model.AuditEvent) (model.UserTableDB, error)
+	FollowUser(followerId string, followedId string, audit ...model.AuditEvent) error
 	UnFollowUser(followerId string, followedId string) error
 	CheckFollow(followerId string, followedId string) (bool, error)
+	BlockUser(blockerID string, blockedID string) error
+	UnblockUser(blockerID string, blockedID string) error
+	MuteUser(muterID string, mutedID string) error
+	UnmuteUser(muterID string, mutedID string) error
+	ListBlocked(uid string) ([]model.Profile, error)
+	ListMuted(uid string) ([]model.Profile, error)
+	GetRelationship(viewerID string, targetID string) (model.Relationship, error)
+	RequestFollow(followerID string, targetID string, audit ...model.AuditEvent) (model.FollowState, error)
+	ListFollowRequests(uid string) ([]model.FollowRequest, error)
+	ApproveFollowRequest(targetID string, requesterID string, audit ...model.AuditEvent) error
+	RejectFollowRequest(targetID string, requesterID string, audit ...model.AuditEvent) error
+	ApproveAllFollowRequests(uid string) error
+	GetUserAccess(uid string) (model.UserAccess, error)
+	ListUsers(filter model.AdminUserFilter) ([]model.AdminUser, int, error)
+	SetSuspended(uid string, suspended bool) (model.AdminUser, error)
+	SetRole(uid string, role string) (model.AdminUser, error)
+}
+
+// userColumns lists the users table columns in the order scanUser expects.
+const userColumns = `id, username, email, password_hash, password_salt, bio, image, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_step, role, suspended_at, private`
+
+func scanUser(row pgx.Row, u *model.UserTableDB) error {
+	return row.Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.PasswordSalt, &u.Bio, &u.Image, &u.CreatedAt, &u.UpdatedAt, &u.EmailVerified, &u.TOTPSecret, &u.TOTPEnabled, &u.TOTPLastStep, &u.Role, &u.SuspendedAt, &u.Private)
 }
 
 type PostgresUserStorage struct {
@@ -39,7 +93,9 @@ func NewPostgresUserStorage(db *pgxpool.Pool, log *slog.Logger) *PostgresUserSto
 	return &PostgresUserStorage{db: db, log: log}
 }
 
-func (s PostgresUserStorage) RegisterUser(username string, email string, password string) error {
+// RegisterUser creates a password account. The audit events are stored with
+// the account, naming the new user as actor and target.
+func (s PostgresUserStorage) RegisterUser(username string, email string, password string, audit ...model.AuditEvent) error {
 	const op = "PostgresUserStorage.RegisterUser"
 
 	passwd, err := security.GeneratePasswd(password)
@@ -59,7 +115,7 @@ func (s PostgresUserStorage) RegisterUser(username string, email string, passwor
 		UpdatedAt:    time.Now(),
 	}
 
-	err = s.AddUser(user)
+	err = s.AddUser(user, audit...)
 	if err != nil {
 		s.log.Error("failed to add user", slog.String("op", op), slog.String("error", err.Error()))
 		return errors.Wrap(err, "failed to add user")
@@ -69,12 +125,32 @@ func (s PostgresUserStorage) RegisterUser(username string, email string, passwor
 	return nil
 }
 
-func (s PostgresUserStorage) AddUser(u model.UserTableDB) error {
+// AddUser inserts u together with the audit events, which name the new user
+// as actor and target.
+func (s PostgresUserStorage) AddUser(u model.UserTableDB, audit ...model.AuditEvent) error {
 	const op = "PostgresUserStorage.AddUser"
 
 	ctx := context.Background()
-	query := `INSERT INTO users (username, email, password_hash, password_salt, bio, image) VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := s.db.Exec(ctx, query, u.Username, u.Email, u.PasswordHash, u.PasswordSalt, u.Bio, u.Image)
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	reserved, err := usernameReserved(ctx, tx, u.Username, "")
+	if err != nil {
+		s.log.Error("failed to check username reservation", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to check username reservation")
+	}
+	if reserved {
+		s.log.Warn("username is reserved", slog.String("op", op), slog.String("username", u.Username))
+		return ErrUsernameAlreadyExists
+	}
+
+	query := `INSERT INTO users (username, email, password_hash, password_salt, bio, image) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	var id string
+	err = tx.QueryRow(ctx, query, u.Username, u.Email, u.PasswordHash, u.PasswordSalt, u.Bio, u.Image).Scan(&id)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
@@ -93,10 +169,32 @@ func (s PostgresUserStorage) AddUser(u model.UserTableDB) error {
 		return errors.Wrap(err, "failed to execute query")
 	}
 
+	if err := recordAudit(ctx, tx, byUser(id, audit)...); err != nil {
+		s.log.Error("failed to record audit event", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to record audit event")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
 	s.log.Info("user added successfully", slog.String("op", op), slog.String("username", u.Username))
 	return nil
 }
 
+// byUser makes uid, which the caller only learns from the action, the actor
+// and target of events.
+func byUser(uid string, events []model.AuditEvent) []model.AuditEvent {
+	out := make([]model.AuditEvent, len(events))
+	for i, event := range events {
+		event.ActorID = &uid
+		event.TargetID = uid
+		out[i] = event
+	}
+	return out
+}
+
 func (s PostgresUserStorage) GetUserForApi(uid string) (model.User, error) {
 	const op = "PostgresUserStorage.GetUserForApi"
 
@@ -106,11 +204,11 @@ func (s PostgresUserStorage) GetUserForApi(uid string) (model.User, error) {
 	}
 
 	ctx := context.Background()
-	query := `SELECT * FROM users WHERE id = $1 LIMIT 1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 LIMIT 1`
 	row := s.db.QueryRow(ctx, query, uid)
 
 	var userDB model.UserTableDB
-	err := row.Scan(&userDB.ID, &userDB.Username, &userDB.Email, &userDB.PasswordHash, &userDB.PasswordSalt, &userDB.Bio, &userDB.Image, &userDB.CreatedAt, &userDB.UpdatedAt)
+	err := scanUser(row, &userDB)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			s.log.Warn("user not found", slog.String("op", op), slog.String("userID", uid))
@@ -121,13 +219,15 @@ func (s PostgresUserStorage) GetUserForApi(uid string) (model.User, error) {
 	}
 
 	user := model.User{
-		ID:        userDB.ID,
-		Username:  userDB.Username,
-		Email:     userDB.Email,
-		Bio:       userDB.Bio,
-		Image:     userDB.Image,
-		CreatedAt: userDB.CreatedAt,
-		UpdatedAt: userDB.UpdatedAt,
+		ID:            userDB.ID,
+		Username:      userDB.Username,
+		Email:         userDB.Email,
+		Bio:           userDB.Bio,
+		Image:         userDB.Image,
+		EmailVerified: userDB.EmailVerified,
+		Private:       userDB.Private,
+		CreatedAt:     userDB.CreatedAt,
+		UpdatedAt:     userDB.UpdatedAt,
 	}
 
 	s.log.Debug("user found", slog.String("op", op), slog.String("userID", uid))
@@ -148,11 +248,11 @@ func (s PostgresUserStorage) GetUserForAuth(email string, username string) (mode
 	var err error
 
 	if email == "" {
-		query = `SELECT * FROM users WHERE username = $1 LIMIT 1`
+		query = `SELECT ` + userColumns + ` FROM users WHERE username = $1 LIMIT 1`
 		row, err = s.db.Query(ctx, query, username)
 		s.log.Debug("querying by username", slog.String("op", op), slog.String("username", username))
 	} else {
-		query = `SELECT * FROM users WHERE email = $1 LIMIT 1`
+		query = `SELECT ` + userColumns + ` FROM users WHERE email = $1 LIMIT 1`
 		row, err = s.db.Query(ctx, query, email)
 		s.log.Debug("querying by email", slog.String("op", op), slog.String("email", email))
 	}
@@ -164,7 +264,7 @@ func (s PostgresUserStorage) GetUserForAuth(email string, username string) (mode
 
 	var userDB model.UserTableDB
 	if row.Next() {
-		err = row.Scan(&userDB.ID, &userDB.Username, &userDB.Email, &userDB.PasswordHash, &userDB.PasswordSalt, &userDB.Bio, &userDB.Image, &userDB.CreatedAt, &userDB.UpdatedAt)
+		err = scanUser(row, &userDB)
 		if err != nil {
 			s.log.Error("failed to scan user data", slog.String("op", op), slog.String("error", err.Error()))
 			return model.UserTableDB{}, errors.Wrap(err, "failed to scan user data")
@@ -187,11 +287,11 @@ func (s PostgresUserStorage) GetUserForUpdate(id string) (model.UserTableDB, err
 	}
 
 	ctx := context.Background()
-	query := `SELECT * FROM users WHERE id = $1 LIMIT 1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 LIMIT 1`
 	row := s.db.QueryRow(ctx, query, id)
 
 	var userDB model.UserTableDB
-	err := row.Scan(&userDB.ID, &userDB.Username, &userDB.Email, &userDB.PasswordHash, &userDB.PasswordSalt, &userDB.Bio, &userDB.Image, &userDB.CreatedAt, &userDB.UpdatedAt)
+	err := scanUser(row, &userDB)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			s.log.Warn("user not found", slog.String("op", op), slog.String("userID", id))
@@ -205,38 +305,13 @@ func (s PostgresUserStorage) GetUserForUpdate(id string) (model.UserTableDB, err
 	return userDB, nil
 }
 
-func (s PostgresUserStorage) DeleteUser(email string, username string) error {
-	const op = "PostgresUserStorage.DeleteUser"
-
-	if email == "" && username == "" {
-		s.log.Warn("empty credentials provided", slog.String("op", op))
-		return errors.New("email or username not provided")
-	}
-
-	query := `DELETE FROM users WHERE email = $1 OR username = $2`
-	ctx := context.Background()
-	result, err := s.db.Exec(ctx, query, email, username)
-	if err != nil {
-		s.log.Error("failed to delete user", slog.String("op", op), slog.String("error", err.Error()))
-		return errors.Wrap(err, "failed to delete user")
-	}
-
-	if result.RowsAffected() == 0 {
-		s.log.Warn("no user deleted", slog.String("op", op))
-		return ErrUserNotFound
-	}
-
-	s.log.Info("user deleted", slog.String("op", op), slog.String("email", email), slog.String("username", username))
-	return nil
-}
-
 func (s PostgresUserStorage) UpdateUser(u model.UserTableDB) error {
 	const op = "PostgresUserStorage.UpdateUser"
 
-	query := `UPDATE users SET username = $1, email = $2, password_hash = $3, password_salt = $4, bio = $5, image = $6, updated_at = $7 WHERE id = $8`
+	query := `UPDATE users SET username = $1, email = $2, password_hash = $3, password_salt = $4, bio = $5, image = $6, updated_at = $7, email_verified = $8, private = $9 WHERE id = $10`
 	ctx := context.Background()
 
-	result, err := s.db.Exec(ctx, query, u.Username, u.Email, u.PasswordHash, u.PasswordSalt, u.Bio, u.Image, time.Now(), u.ID)
+	result, err := s.db.Exec(ctx, query, u.Username, u.Email, u.PasswordHash, u.PasswordSalt, u.Bio, u.Image, time.Now(), u.EmailVerified, u.Private, u.ID)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
@@ -264,6 +339,26 @@ func (s PostgresUserStorage) UpdateUser(u model.UserTableDB) error {
 	return nil
 }
 
+func (s PostgresUserStorage) UpdatePassword(uid string, hash string, salt string) error {
+	const op = "PostgresUserStorage.UpdatePassword"
+
+	query := `UPDATE users SET password_hash = $1, password_salt = $2 WHERE id = $3`
+	ctx := context.Background()
+	result, err := s.db.Exec(ctx, query, hash, salt, uid)
+	if err != nil {
+		s.log.Error("failed to update password", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to update password")
+	}
+
+	if result.RowsAffected() == 0 {
+		s.log.Warn("no user updated", slog.String("op", op), slog.String("userID", uid))
+		return ErrUserNotFound
+	}
+
+	s.log.Info("password updated", slog.String("op", op), slog.String("userID", uid))
+	return nil
+}
+
 func (s PostgresUserStorage) AddToken(token string, uid string) error {
 	const op = "PostgresUserStorage.AddToken"
 
@@ -374,7 +469,229 @@ func (s PostgresUserStorage) FindTokenByUID(uid string) (model.UserAuthToken, er
 	return tokenDB, nil
 }
 
-func (s PostgresUserStorage) FollowUser(followerId string, followedId string) error {
+func (s PostgresUserStorage) RevokeToken(jti string, uid string, expiresAt time.Time) error {
+	const op = "PostgresUserStorage.RevokeToken"
+
+	if jti == "" || uid == "" {
+		s.log.Warn("empty jti or user ID", slog.String("op", op))
+		return errors.New("jti or user ID not provided")
+	}
+
+	query := `INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES ($1, $2, $3) ON CONFLICT (jti) DO NOTHING`
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, query, jti, uid, expiresAt)
+	if err != nil {
+		s.log.Error("failed to revoke token", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to revoke token")
+	}
+
+	s.log.Info("token revoked", slog.String("op", op), slog.String("userID", uid))
+	return nil
+}
+
+func (s PostgresUserStorage) GetRevokedTokens() (map[string]time.Time, error) {
+	const op = "PostgresUserStorage.GetRevokedTokens"
+
+	query := `SELECT jti, expires_at FROM revoked_tokens WHERE expires_at > now()`
+	ctx := context.Background()
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		s.log.Error("failed to query revoked tokens", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, errors.Wrap(err, "failed to query revoked tokens")
+	}
+	defer rows.Close()
+
+	revoked := make(map[string]time.Time)
+	for rows.Next() {
+		var jti string
+		var expiresAt time.Time
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			s.log.Error("failed to scan revoked token", slog.String("op", op), slog.String("error", err.Error()))
+			return nil, errors.Wrap(err, "failed to scan revoked token")
+		}
+		revoked[jti] = expiresAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read revoked tokens")
+	}
+
+	s.log.Debug("revoked tokens loaded", slog.String("op", op), slog.Int("count", len(revoked)))
+	return revoked, nil
+}
+
+func (s PostgresUserStorage) GetTokensByUID(uid string) ([]model.UserAuthToken, error) {
+	const op = "PostgresUserStorage.GetTokensByUID"
+
+	if uid == "" {
+		s.log.Warn("empty user ID provided", slog.String("op", op))
+		return nil, errors.New("user ID not provided")
+	}
+
+	query := `SELECT * FROM tokens WHERE user_id = $1`
+	ctx := context.Background()
+	rows, err := s.db.Query(ctx, query, uid)
+	if err != nil {
+		s.log.Error("failed to query tokens", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, errors.Wrap(err, "failed to query tokens")
+	}
+	defer rows.Close()
+
+	var tokens []model.UserAuthToken
+	for rows.Next() {
+		var tokenDB model.UserAuthToken
+		err = rows.Scan(&tokenDB.ID, &tokenDB.Token, &tokenDB.CreatedAt, &tokenDB.EndDate, &tokenDB.UID)
+		if err != nil {
+			s.log.Error("failed to scan token data", slog.String("op", op), slog.String("error", err.Error()))
+			return nil, errors.Wrap(err, "failed to scan token data")
+		}
+		tokens = append(tokens, tokenDB)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read tokens")
+	}
+
+	return tokens, nil
+}
+
+func (s PostgresUserStorage) CreatePasswordReset(uid string, tokenHash string, expiresAt time.Time) error {
+	const op = "PostgresUserStorage.CreatePasswordReset"
+
+	if uid == "" || tokenHash == "" {
+		s.log.Warn("empty user ID or token hash", slog.String("op", op))
+		return errors.New("user ID or token hash not provided")
+	}
+
+	query := `INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, query, tokenHash, uid, expiresAt)
+	if err != nil {
+		s.log.Error("failed to create password reset", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to create password reset")
+	}
+
+	s.log.Info("password reset created", slog.String("op", op), slog.String("userID", uid))
+	return nil
+}
+
+// FindPasswordReset returns the user a usable reset token belongs to without
+// consuming it.
+func (s PostgresUserStorage) FindPasswordReset(tokenHash string) (string, error) {
+	const op = "PostgresUserStorage.FindPasswordReset"
+
+	query := `SELECT user_id FROM password_resets WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()`
+	ctx := context.Background()
+	var uid string
+	err := s.db.QueryRow(ctx, query, tokenHash).Scan(&uid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrResetTokenInvalid
+		}
+		s.log.Error("failed to find password reset", slog.String("op", op), slog.String("error", err.Error()))
+		return "", errors.Wrap(err, "failed to find password reset")
+	}
+	return uid, nil
+}
+
+// ConsumePasswordReset marks an unused, unexpired reset token as used and
+// returns the ID of the user it was issued for. The single UPDATE makes the
+// token usable exactly once even under concurrent requests.
+func (s PostgresUserStorage) ConsumePasswordReset(tokenHash string) (string, error) {
+	const op = "PostgresUserStorage.ConsumePasswordReset"
+
+	if tokenHash == "" {
+		s.log.Warn("empty token hash provided", slog.String("op", op))
+		return "", ErrResetTokenInvalid
+	}
+
+	query := `UPDATE password_resets SET used_at = now() WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() RETURNING user_id`
+	ctx := context.Background()
+	var uid string
+	err := s.db.QueryRow(ctx, query, tokenHash).Scan(&uid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.log.Warn("password reset token not usable", slog.String("op", op))
+			return "", ErrResetTokenInvalid
+		}
+		s.log.Error("failed to consume password reset", slog.String("op", op), slog.String("error", err.Error()))
+		return "", errors.Wrap(err, "failed to consume password reset")
+	}
+
+	s.log.Info("password reset consumed", slog.String("op", op), slog.String("userID", uid))
+	return uid, nil
+}
+
+func (s PostgresUserStorage) CreateEmailVerification(uid string, email string, tokenHash string, expiresAt time.Time) error {
+	const op = "PostgresUserStorage.CreateEmailVerification"
+
+	if uid == "" || email == "" || tokenHash == "" {
+		s.log.Warn("empty user ID, email or token hash", slog.String("op", op))
+		return errors.New("user ID, email or token hash not provided")
+	}
+
+	query := `INSERT INTO email_verifications (token_hash, user_id, email, expires_at) VALUES ($1, $2, $3, $4)`
+	ctx := context.Background()
+	_, err := s.db.Exec(ctx, query, tokenHash, uid, email, expiresAt)
+	if err != nil {
+		s.log.Error("failed to create email verification", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to create email verification")
+	}
+
+	s.log.Info("email verification created", slog.String("op", op), slog.String("userID", uid))
+	return nil
+}
+
+// ConsumeEmailVerification marks the token as used and flags the user's email
+// as verified, provided the address has not changed since the token was sent.
+func (s PostgresUserStorage) ConsumeEmailVerification(tokenHash string, audit ...model.AuditEvent) (string, error) {
+	const op = "PostgresUserStorage.ConsumeEmailVerification"
+
+	if tokenHash == "" {
+		s.log.Warn("empty token hash provided", slog.String("op", op))
+		return "", ErrVerificationInvalid
+	}
+
+	query := `WITH v AS (
+		UPDATE email_verifications SET used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id, email
+	)
+	UPDATE users u SET email_verified = TRUE, updated_at = now()
+	FROM v WHERE u.id = v.user_id AND u.email = v.email
+	RETURNING u.id`
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return "", errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var uid string
+	err = tx.QueryRow(ctx, query, tokenHash).Scan(&uid)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.log.Warn("email verification token not usable", slog.String("op", op))
+			return "", ErrVerificationInvalid
+		}
+		s.log.Error("failed to consume email verification", slog.String("op", op), slog.String("error", err.Error()))
+		return "", errors.Wrap(err, "failed to consume email verification")
+	}
+	if err := recordAudit(ctx, tx, byUser(uid, audit)...); err != nil {
+		s.log.Error("failed to record audit event", slog.String("op", op), slog.String("error", err.Error()))
+		return "", errors.Wrap(err, "failed to record audit event")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return "", errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.log.Info("email verified", slog.String("op", op), slog.String("userID", uid))
+	return uid, nil
+}
+
+// FollowUser subscribes followerId to followedId and stores the audit events
+// with the subscription.
+func (s PostgresUserStorage) FollowUser(followerId string, followedId string, audit ...model.AuditEvent) error {
 	const op = "PostgresUserStorage.FollowUser"
 
 	if followerId == "" || followedId == "" {
@@ -382,9 +699,25 @@ func (s PostgresUserStorage) FollowUser(followerId string, followedId string) er
 		return errors.New("follower ID or followed ID not provided")
 	}
 
-	query := `INSERT INTO subscriptions (sub_id, target_user_id) VALUES ($1, $2)`
+	// A block in either direction prevents the follow.
+	query := `INSERT INTO subscriptions (sub_id, target_user_id)
+		SELECT $1, $2 WHERE NOT EXISTS (
+			SELECT 1 FROM user_blocks WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1))`
 	ctx := context.Background()
-	_, err := s.db.Exec(ctx, query, followerId, followedId)
+	err := withAudit(ctx, s.db, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, followerId, followedId)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return ErrBlocked
+		}
+		return nil
+	}, audit...)
+	if errors.Is(err, ErrBlocked) {
+		s.log.Warn("follow prevented by block", slog.String("op", op), slog.String("followerID", followerId), slog.String("followedID", followedId))
+		return ErrBlocked
+	}
 	if err != nil {
 		s.log.Error("failed to follow user", slog.String("op", op), slog.String("error", err.Error()))
 		return errors.Wrap(err, "failed to follow user")
@@ -402,15 +735,19 @@ func (s PostgresUserStorage) UnFollowUser(followerId string, followedId string)
 		return errors.New("follower ID or followed ID not provided")
 	}
 
-	query := `DELETE FROM subscriptions WHERE sub_id = $1 AND target_user_id = $2`
+	// Unfollowing also withdraws a pending follow request.
+	query := `WITH s AS (DELETE FROM subscriptions WHERE sub_id = $1 AND target_user_id = $2 RETURNING 1),
+		r AS (DELETE FROM follow_requests WHERE requester_id = $1 AND target_id = $2 RETURNING 1)
+		SELECT (SELECT count(*) FROM s) + (SELECT count(*) FROM r)`
 	ctx := context.Background()
-	result, err := s.db.Exec(ctx, query, followerId, followedId)
+	var removed int
+	err := s.db.QueryRow(ctx, query, followerId, followedId).Scan(&removed)
 	if err != nil {
 		s.log.Error("failed to unfollow user", slog.String("op", op), slog.String("error", err.Error()))
 		return errors.Wrap(err, "failed to unfollow user")
 	}
 
-	if result.RowsAffected() == 0 {
+	if removed == 0 {
 		s.log.Warn("no subscription found to delete", slog.String("op", op))
 		return errors.New("subscription not found")
 	}
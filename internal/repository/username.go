@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"rwa/internal/model"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+// queryRower is implemented by both the pool and transactions.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// execer is implemented by both the pool and transactions.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// usernameReserved reports whether username was released recently by
+// someone other than uid (pass "" for a new account).
+func usernameReserved(ctx context.Context, q queryRower, username string, uid string) (bool, error) {
+	var reserved bool
+	err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM username_history
+		WHERE username = $1 AND reserved_until > now() AND (user_id IS NULL OR user_id::text <> $2))`, username, uid).Scan(&reserved)
+	return reserved, err
+}
+
+// releaseUsername records that uid gave up username, keeping it reserved
+// until reservedUntil.
+func releaseUsername(ctx context.Context, tx pgx.Tx, username string, uid string, reservedUntil time.Time) error {
+	_, err := tx.Exec(ctx, `INSERT INTO username_history (username, user_id, reserved_until) VALUES ($1, $2, $3)
+		ON CONFLICT (username) DO UPDATE SET user_id = EXCLUDED.user_id, released_at = now(), reserved_until = EXCLUDED.reserved_until`,
+		username, uid, reservedUntil)
+	return err
+}
+
+// UpdateProfile saves the editable user fields (username, email, bio, image,
+// email verification and privacy) in one transaction. A changed username
+// keeps the old one reserved for the user until reservedUntil; a user may
+// take back their own released names. The audit events are stored with the
+// change.
+func (s PostgresUserStorage) UpdateProfile(u model.UserTableDB, reservedUntil time.Time, audit ...model.AuditEvent) error {
+	const op = "PostgresUserStorage.UpdateProfile"
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var current string
+	err = tx.QueryRow(ctx, `SELECT username FROM users WHERE id = $1 FOR UPDATE`, u.ID).Scan(&current)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		s.log.Error("failed to lock user", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to lock user")
+	}
+
+	renamed := current != u.Username
+	if renamed {
+		reserved, err := usernameReserved(ctx, tx, u.Username, u.ID)
+		if err != nil {
+			s.log.Error("failed to check username reservation", slog.String("op", op), slog.String("error", err.Error()))
+			return errors.Wrap(err, "failed to check username reservation")
+		}
+		if reserved {
+			s.log.Warn("username is reserved", slog.String("op", op), slog.String("username", u.Username))
+			return ErrUsernameAlreadyExists
+		}
+
+		// Whatever history the new name has is either ours or expired.
+		_, err = tx.Exec(ctx, `DELETE FROM username_history WHERE username = $1`, u.Username)
+		if err != nil {
+			s.log.Error("failed to clear username history", slog.String("op", op), slog.String("error", err.Error()))
+			return errors.Wrap(err, "failed to clear username history")
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users SET username = $1, email = $2, bio = $3, image = $4, email_verified = $5, private = $6, updated_at = now() WHERE id = $7`,
+		u.Username, u.Email, u.Bio, u.Image, u.EmailVerified, u.Private, u.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			if pgErr.ConstraintName == "users_email_key" {
+				s.log.Warn("email already exists", slog.String("op", op), slog.String("email", u.Email))
+				return ErrEmailAlreadyExists
+			}
+			if pgErr.ConstraintName == "users_username_key" {
+				s.log.Warn("username already exists", slog.String("op", op), slog.String("username", u.Username))
+				return ErrUsernameAlreadyExists
+			}
+		}
+		s.log.Error("failed to update user", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to update user")
+	}
+
+	if renamed {
+		if err := releaseUsername(ctx, tx, current, u.ID, reservedUntil); err != nil {
+			s.log.Error("failed to record username history", slog.String("op", op), slog.String("error", err.Error()))
+			return errors.Wrap(err, "failed to record username history")
+		}
+	}
+
+	if err := recordAudit(ctx, tx, audit...); err != nil {
+		s.log.Error("failed to record audit event", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to record audit event")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit transaction", slog.String("op", op), slog.String("error", err.Error()))
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	s.log.Info("user profile updated", slog.String("op", op), slog.String("userID", u.ID), slog.Bool("renamed", renamed))
+	return nil
+}
+
+// ResolveFormerUsername returns the current username of the user who
+// recently gave up username, or ErrUserNotFound.
+func (s PostgresUserStorage) ResolveFormerUsername(username string) (string, error) {
+	const op = "PostgresUserStorage.ResolveFormerUsername"
+
+	query := `SELECT u.username FROM username_history h JOIN users u ON u.id = h.user_id
+		WHERE h.username = $1 AND h.reserved_until > now()`
+	ctx := context.Background()
+	var current string
+	err := s.db.QueryRow(ctx, query, username).Scan(&current)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		s.log.Error("failed to resolve former username", slog.String("op", op), slog.String("error", err.Error()))
+		return "", errors.Wrap(err, "failed to resolve former username")
+	}
+	return current, nil
+}
@@ -0,0 +1,47 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// GenerateOpaqueToken returns a random URL-safe token for single-use links
+// and API credentials. Only its HashOpaqueToken digest should be stored.
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PersonalTokenPrefix marks personal access tokens so AuthMiddleware can tell
+// them apart from PASETO session tokens without trying to decrypt them.
+const PersonalTokenPrefix = "pat_"
+
+func GeneratePersonalToken() (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalTokenPrefix + token, nil
+}
+
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// PseudonymizeEmail returns a keyed digest of an address that somebody
+// typed, so repeated attempts against it can be correlated in logs without
+// storing what an unauthenticated client sent. Keying with the server key
+// keeps the digest from being reversed with a list of known addresses.
+func PseudonymizeEmail(email string) string {
+	mac := hmac.New(sha256.New, pasetoSymmetricKey)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,25 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPseudonymizeEmail(t *testing.T) {
+	if err := Init(strings.Repeat("k", 32)); err != nil {
+		t.Fatal(err)
+	}
+	a := PseudonymizeEmail("Gopher@Example.com ")
+	if a != PseudonymizeEmail("gopher@example.com") {
+		t.Error("digest depends on case or surrounding space")
+	}
+	if a == PseudonymizeEmail("other@example.com") {
+		t.Error("different addresses share a digest")
+	}
+	if a == HashOpaqueToken("gopher@example.com") {
+		t.Error("digest is not keyed")
+	}
+	if strings.Contains(a, "gopher") || len(a) != 64 {
+		t.Errorf("digest = %q", a)
+	}
+}
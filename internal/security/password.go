@@ -2,9 +2,15 @@ package security
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
-	"golang.org/x/crypto/argon2"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
 )
 
 type PasswordObj struct {
@@ -12,8 +18,37 @@ type PasswordObj struct {
 	Salt string
 }
 
-func generateSalt() ([]byte, error) {
-	salt := make([]byte, 16)
+// Argon2Params are the argon2id cost settings used for new hashes. Stored
+// hashes carry their own parameters in PHC format, so changing these only
+// affects passwords hashed (or rehashed) afterwards.
+type Argon2Params struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	KeyLen  uint32
+	SaltLen uint32
+}
+
+var DefaultArgon2Params = Argon2Params{Time: 1, Memory: 64 * 1024, Threads: 4, KeyLen: 32, SaltLen: 16}
+
+var argon2Params = DefaultArgon2Params
+
+// legacyArgon2Params are the parameters of hashes written before the PHC
+// format, which were stored as bare base64 with the salt in its own column.
+var legacyArgon2Params = Argon2Params{Time: 1, Memory: 64 * 1024, Threads: 4, KeyLen: 32, SaltLen: 16}
+
+var errInvalidHash = errors.New("invalid password hash format")
+
+func SetArgon2Params(p Argon2Params) error {
+	if p.Time == 0 || p.Memory < 8*uint32(p.Threads) || p.Threads == 0 || p.KeyLen < 16 || p.SaltLen < 8 {
+		return errors.New("invalid argon2 parameters")
+	}
+	argon2Params = p
+	return nil
+}
+
+func generateSalt(n uint32) ([]byte, error) {
+	salt := make([]byte, n)
 
 	_, err := rand.Read(salt)
 	if err != nil {
@@ -22,24 +57,104 @@ func generateSalt() ([]byte, error) {
 	return salt, nil
 }
 
+// GeneratePasswd hashes pass with the current parameters. Hash holds the
+// self-describing PHC string ($argon2id$v=19$m=...,t=...,p=...$salt$key);
+// Salt is empty because the salt is embedded in Hash.
 func GeneratePasswd(pass string) (PasswordObj, error) {
-	salt, err := generateSalt()
+	p := argon2Params
+	salt, err := generateSalt(p.SaltLen)
 	if err != nil {
 
 		log.Printf("Error generating salt: %v", err)
 		return PasswordObj{}, err
 	}
-	key := argon2.IDKey([]byte(pass), salt, 1, 64*1024, 4, 32)
-	base64Hash := base64.RawStdEncoding.EncodeToString(key)
-	base64Salt := base64.RawStdEncoding.EncodeToString(salt)
-	return PasswordObj{Hash: base64Hash, Salt: base64Salt}, nil
+	key := argon2.IDKey([]byte(pass), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))
+	return PasswordObj{Hash: encoded, Salt: ""}, nil
+}
+
+// CheckPassword verifies passwd against a stored hash. needsRehash reports
+// that the hash is valid but uses the legacy layout or outdated parameters.
+func CheckPassword(passwd string, passwdDB string, salt string) (ok bool, needsRehash bool) {
+	if !strings.HasPrefix(passwdDB, "$argon2id$") {
+		saltByte, err := base64.RawStdEncoding.DecodeString(salt)
+		if err != nil {
+			return false, false
+		}
+		want, err := base64.RawStdEncoding.DecodeString(passwdDB)
+		if err != nil {
+			return false, false
+		}
+		p := legacyArgon2Params
+		got := argon2.IDKey([]byte(passwd), saltByte, p.Time, p.Memory, p.Threads, p.KeyLen)
+		return subtle.ConstantTimeCompare(got, want) == 1, true
+	}
+
+	p, saltByte, want, err := decodePHC(passwdDB)
+	if err != nil {
+		return false, false
+	}
+	got := argon2.IDKey([]byte(passwd), saltByte, p.Time, p.Memory, p.Threads, uint32(len(want)))
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return false, false
+	}
+	return true, p != argon2Params
 }
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// DummyCheckPassword runs the same argon2 work as CheckPassword against a
+// fixed hash, so a login for an unknown account takes as long as one with a
+// wrong password. The hash is made on first use, after SetArgon2Params.
+func DummyCheckPassword(passwd string) {
+	dummyHashOnce.Do(func() {
+		p, err := GeneratePasswd("conduit-dummy-password")
+		if err != nil {
+			log.Printf("Error generating dummy hash: %v", err)
+			return
+		}
+		dummyHash = p.Hash
+	})
+	CheckPassword(passwd, dummyHash, "")
+}
+
 func ComparePasswords(passwd string, passwdDB string, salt string) bool {
-	saltByte, _ := base64.RawStdEncoding.DecodeString(salt)
-	userPassHash := argon2.IDKey([]byte(passwd), saltByte, 1, 64*1024, 4, 32)
-	base64Hash := base64.RawStdEncoding.EncodeToString(userPassHash)
-	if base64Hash != passwdDB {
-		return false
+	ok, _ := CheckPassword(passwd, passwdDB, salt)
+	return ok
+}
+
+func decodePHC(encoded string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2Params{}, nil, nil, errInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, errInvalidHash
+	}
+
+	var p Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return Argon2Params{}, nil, nil, errInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, errInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, errInvalidHash
 	}
-	return true
+	p.SaltLen = uint32(len(salt))
+	p.KeyLen = uint32(len(key))
+	return p, salt, key, nil
 }
@@ -0,0 +1,101 @@
+package security
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Cheap parameters keep the tests fast; production defaults are exercised
+// through the legacy path below.
+var testParams = Argon2Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+
+func withParams(t *testing.T, p Argon2Params) {
+	t.Helper()
+	prev := argon2Params
+	if err := SetArgon2Params(p); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { argon2Params = prev })
+}
+
+func TestGeneratePasswdPHC(t *testing.T) {
+	withParams(t, testParams)
+
+	obj, err := GeneratePasswd("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(obj.Hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected encoding %q", obj.Hash)
+	}
+
+	if ok, rehash := CheckPassword("correct horse", obj.Hash, obj.Salt); !ok || rehash {
+		t.Errorf("CheckPassword = %v, %v; want true, false", ok, rehash)
+	}
+	if ok, _ := CheckPassword("wrong horse", obj.Hash, obj.Salt); ok {
+		t.Error("wrong password accepted")
+	}
+}
+
+func TestCheckPasswordRehashOnParamChange(t *testing.T) {
+	withParams(t, testParams)
+	obj, err := GeneratePasswd("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stronger := testParams
+	stronger.Time = 2
+	withParams(t, stronger)
+	if ok, rehash := CheckPassword("secret", obj.Hash, obj.Salt); !ok || !rehash {
+		t.Errorf("CheckPassword = %v, %v; want true, true", ok, rehash)
+	}
+}
+
+func TestCheckPasswordLegacy(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte("legacy"), salt, 1, 64*1024, 4, 32)
+	hash := base64.RawStdEncoding.EncodeToString(key)
+	saltB64 := base64.RawStdEncoding.EncodeToString(salt)
+
+	if ok, rehash := CheckPassword("legacy", hash, saltB64); !ok || !rehash {
+		t.Errorf("CheckPassword = %v, %v; want true, true", ok, rehash)
+	}
+	if ok, _ := CheckPassword("other", hash, saltB64); ok {
+		t.Error("wrong password accepted for legacy hash")
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	prev := passwordPolicy
+	SetPasswordPolicy(PasswordPolicy{MinLength: 8, MaxLength: 64, MinClasses: 3, DisallowPersonal: true, CheckBreached: true})
+	t.Cleanup(func() { SetPasswordPolicy(prev) })
+
+	cases := []struct {
+		password string
+		problems int
+	}{
+		{"Tr0ub4dor&3", 0},
+		{"short1A", 1},
+		{"alllowercase", 1},
+		{"Golang-Gopher1", 1},
+		{"Password123", 1},
+	}
+	for _, c := range cases {
+		got := ValidatePassword(c.password, "golang", "gopher@example.com")
+		if len(got) != c.problems {
+			t.Errorf("ValidatePassword(%q) = %v, want %d problems", c.password, got, c.problems)
+		}
+	}
+}
+
+func TestDummyCheckPasswordDoesWork(t *testing.T) {
+	withParams(t, testParams)
+	DummyCheckPassword("anything")
+	if _, _, _, err := decodePHC(dummyHash); err != nil {
+		t.Fatalf("dummy hash is not a usable argon2id hash: %v", err)
+	}
+}
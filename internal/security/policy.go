@@ -0,0 +1,156 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed breached_passwords.txt
+var bundledBreachedPasswords string
+
+// PasswordPolicy is applied to passwords chosen at registration, password
+// change and password reset.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// MinClasses is how many of lowercase, uppercase, digit and symbol must
+	// appear.
+	MinClasses int
+	// DisallowPersonal rejects passwords containing the username or the
+	// local part of the email.
+	DisallowPersonal bool
+	CheckBreached    bool
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 5, MaxLength: 128, MinClasses: 1, DisallowPersonal: true, CheckBreached: true}
+
+var (
+	passwordPolicy = DefaultPasswordPolicy
+	breached       = mustParseBreached(strings.NewReader(bundledBreachedPasswords))
+)
+
+func SetPasswordPolicy(p PasswordPolicy) {
+	passwordPolicy = p
+}
+
+// LoadBreachedPasswords replaces the bundled breached-password list with the
+// SHA-1 digests in path (one HASH or HASH:COUNT per line, as in the Have I
+// Been Pwned downloads). An empty path keeps the bundled list.
+func LoadBreachedPasswords(path string) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	set, err := parseBreached(f)
+	if err != nil {
+		return err
+	}
+	breached = set
+	return nil
+}
+
+func parseBreached(r io.Reader) (map[string]struct{}, error) {
+	set := make(map[string]struct{})
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		set[strings.ToUpper(line)] = struct{}{}
+	}
+	return set, sc.Err()
+}
+
+func mustParseBreached(r io.Reader) map[string]struct{} {
+	set, err := parseBreached(r)
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+func IsBreachedPassword(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	_, ok := breached[strings.ToUpper(hex.EncodeToString(sum[:]))]
+	return ok
+}
+
+// ValidatePassword checks password against the configured policy and returns
+// one message per violated rule, or nil if it is acceptable.
+func ValidatePassword(password string, username string, email string) []string {
+	p := passwordPolicy
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("is too short (minimum is %d characters)", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("is too long (maximum is %d characters)", p.MaxLength))
+	}
+
+	if classes := characterClasses(password); classes < p.MinClasses {
+		problems = append(problems, fmt.Sprintf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses))
+	}
+
+	if p.DisallowPersonal && containsPersonal(password, username, email) {
+		problems = append(problems, "must not contain your username or email")
+	}
+
+	if p.CheckBreached && IsBreachedPassword(password) {
+		problems = append(problems, "has appeared in a data breach, choose a different one")
+	}
+
+	return problems
+}
+
+func characterClasses(s string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range s {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// containsPersonal ignores fragments shorter than three characters, which
+// would reject too many unrelated passwords.
+func containsPersonal(password string, username string, email string) bool {
+	lower := strings.ToLower(password)
+	local := email
+	if i := strings.IndexByte(email, '@'); i >= 0 {
+		local = email[:i]
+	}
+	for _, part := range []string{username, local} {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package security
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// RevocationCache keeps the JTIs of revoked session tokens in memory so that
+// stateless validation does not need a database round trip per request.
+// Entries are dropped once the token they refer to has expired anyway.
+type RevocationCache struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+func NewRevocationCache() *RevocationCache {
+	return &RevocationCache{revoked: make(map[string]time.Time)}
+}
+
+func (c *RevocationCache) IsRevoked(jti string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.revoked[jti]
+	return ok
+}
+
+func (c *RevocationCache) Revoke(jti string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.revoked[jti] = expiresAt
+}
+
+// Merge adds entries loaded from the revocation list and prunes expired ones.
+// Revocations are never undone, so merging instead of replacing keeps local
+// revocations that raced with the load.
+func (c *RevocationCache) Merge(entries map[string]time.Time) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for jti, exp := range entries {
+		c.revoked[jti] = exp
+	}
+	for jti, exp := range c.revoked {
+		if exp.Before(now) {
+			delete(c.revoked, jti)
+		}
+	}
+}
+
+// Run refreshes the cache from load every interval until ctx is cancelled.
+func (c *RevocationCache) Run(ctx context.Context, interval time.Duration, load func() (map[string]time.Time, error), log *slog.Logger) {
+	const op = "security.RevocationCache.Run"
+
+	refresh := func() {
+		entries, err := load()
+		if err != nil {
+			log.Error("failed to refresh revocation list", "op", op, "error", err)
+			return
+		}
+		c.Merge(entries)
+	}
+
+	refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package security
+
+import "time"
+
+// ThrottlePolicy describes how failed logins for one key (an account or a
+// client IP) are slowed down: the first FreeAttempts failures cost nothing,
+// every further failure doubles the wait starting at BaseDelay (capped at
+// MaxDelay), and reaching LockoutAfter failures locks the key for LockoutFor.
+// Failures older than Window are forgotten.
+type ThrottlePolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockoutAfter int
+	LockoutFor   time.Duration
+	Window       time.Duration
+}
+
+// Delay returns how long the key must wait after its failures-th failure.
+func (p ThrottlePolicy) Delay(failures int) time.Duration {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.LockoutFor
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
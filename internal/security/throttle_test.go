@@ -0,0 +1,56 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottlePolicyDelay(t *testing.T) {
+	p := ThrottlePolicy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     10 * time.Second,
+		LockoutAfter: 10,
+		LockoutFor:   15 * time.Minute,
+	}
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{9, 10 * time.Second},
+		{10, 15 * time.Minute},
+		{50, 15 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := p.Delay(c.failures); got != c.want {
+			t.Errorf("Delay(%d) = %v, want %v", c.failures, got, c.want)
+		}
+	}
+}
+
+func TestThrottlePolicyDelayEdges(t *testing.T) {
+	cases := []struct {
+		name     string
+		policy   ThrottlePolicy
+		failures int
+		want     time.Duration
+	}{
+		{"no lockout configured", ThrottlePolicy{BaseDelay: time.Second, MaxDelay: time.Minute}, 1000, time.Minute},
+		{"lockout before backoff starts", ThrottlePolicy{FreeAttempts: 5, LockoutAfter: 2, LockoutFor: time.Hour}, 2, time.Hour},
+		{"base above max", ThrottlePolicy{BaseDelay: time.Hour, MaxDelay: time.Minute}, 1, time.Minute},
+		{"no free attempts", ThrottlePolicy{BaseDelay: time.Second, MaxDelay: time.Minute}, 1, time.Second},
+	}
+	for _, c := range cases {
+		if got := c.policy.Delay(c.failures); got != c.want {
+			t.Errorf("%s: Delay(%d) = %v, want %v", c.name, c.failures, got, c.want)
+		}
+	}
+}
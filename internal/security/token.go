@@ -22,7 +22,10 @@ func Init(key string) error {
 	return nil
 }
 
-func GenerateToken(userUID string) string {
+// GenerateToken issues a session token for userUID. The role is embedded so
+// stateless validation can authorize requests without loading the user;
+// changing a user's role or suspending them revokes their sessions.
+func GenerateToken(userUID string, role string) string {
 
 	now := time.Now()
 	exp := now.Add(time.Hour * 24 * 7)
@@ -38,22 +41,46 @@ func GenerateToken(userUID string) string {
 		Subject:    "test_subject",
 	}
 	jsonToken.Set("uid", userUID)
+	jsonToken.Set("role", role)
 	encrypt, _ := paseto.NewV2().Encrypt(pasetoSymmetricKey, jsonToken, PasetoFooter)
 	return encrypt
 
 }
 
-func DecodeToken(token string) (string, error) {
+// Claims is the subset of the PASETO payload the API relies on.
+type Claims struct {
+	UID       string
+	Role      string
+	Jti       string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+func ParseToken(token string) (Claims, error) {
 	var newJsonToken paseto.JSONToken
 	var newFooter string
 	err := paseto.NewV2().Decrypt(token, pasetoSymmetricKey, &newJsonToken, &newFooter)
 	if err != nil {
-		return "", err
+		return Claims{}, err
 	}
 	if newJsonToken.Expiration.Before(time.Now()) {
-		return "", errors.New("Token expired")
+		return Claims{}, errors.New("Token expired")
+	}
+	return Claims{
+		UID:       newJsonToken.Get("uid"),
+		Role:      newJsonToken.Get("role"),
+		Jti:       newJsonToken.Jti,
+		IssuedAt:  newJsonToken.IssuedAt,
+		ExpiresAt: newJsonToken.Expiration,
+	}, nil
+}
+
+func DecodeToken(token string) (string, error) {
+	claims, err := ParseToken(token)
+	if err != nil {
+		return "", err
 	}
-	return newJsonToken.Get("uid"), nil
+	return claims.UID, nil
 }
 
 func GenerateJTI() string {
@@ -0,0 +1,19 @@
+package security
+
+import "testing"
+
+func TestTokenCarriesRole(t *testing.T) {
+	prev := pasetoSymmetricKey
+	if err := Init("0123456789abcdef0123456789abcdef"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pasetoSymmetricKey = prev })
+
+	claims, err := ParseToken(GenerateToken("uid-1", "moderator"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UID != "uid-1" || claims.Role != "moderator" || claims.Jti == "" {
+		t.Errorf("claims = %+v", claims)
+	}
+}